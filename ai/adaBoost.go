@@ -0,0 +1,297 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	v "github.com/deboshire/exp/math/vector"
+	"math"
+)
+
+// Trains a classifier minimizing weighted error. Weights are positive and
+// sum to 1.
+type WeightedBinaryClassifierTrainer func(features []v.F64, labels []bool, weights v.F64) BinaryClassifier
+
+type AdaBoostKind int
+
+const (
+	// Weak classifiers vote with their class, weighted by alpha computed
+	// from their weighted error.
+	DiscreteAdaBoost AdaBoostKind = iota
+	// Weak classifiers vote with half the log-odds of their estimate of
+	// P(true), alphas are all 1.
+	RealAdaBoost
+)
+
+func (k AdaBoostKind) String() string {
+	switch k {
+	case DiscreteAdaBoost:
+		return "discrete"
+	case RealAdaBoost:
+		return "real"
+	}
+	return fmt.Sprintf("AdaBoostKind(%d)", int(k))
+}
+
+type AdaBoostOptions struct {
+	Kind AdaBoostKind
+	// Maximum number of weak classifiers, at least 1. Discrete AdaBoost
+	// stops earlier when a weak classifier is no better than chance or
+	// makes no errors.
+	Rounds int
+}
+
+// Weak classifier probabilities are clipped to [adaBoostEps, 1-adaBoostEps]
+// and errors of discrete AdaBoost to at least adaBoostEps to keep votes
+// finite.
+const adaBoostEps = 1e-6
+
+// Boosted classifier: sign of the weighted sum of weak classifier votes,
+// h(x) = 1 for true and -1 for false in the discrete variant.
+type AdaBoostClassifier struct {
+	kind AdaBoostKind
+	// Added to the score. Non-zero only when the first weak classifier of
+	// discrete AdaBoost is no better than chance: it is kept with alpha 0
+	// and the classifier falls back to half the log-odds of the classes.
+	prior       float64
+	classifiers []BinaryClassifier
+	alphas      v.F64
+	// Weighted error of every weak classifier on its training weights.
+	weakErrors v.F64
+	// Error rate of the boosted classifier on the training set after every
+	// round.
+	trainingErrors v.F64
+}
+
+// Trains AdaBoost classifier: every round trains a weak classifier with
+// example weights emphasizing the examples its predecessors got wrong.
+// If the first weak classifier of discrete AdaBoost is no better than
+// chance the result predicts the majority class.
+func TrainAdaBoostClassifier(
+	features []v.F64,
+	labels []bool,
+	weak WeightedBinaryClassifierTrainer,
+	o AdaBoostOptions) *AdaBoostClassifier {
+
+	if len(features) == 0 {
+		panic("No training data")
+	}
+	if o.Kind != DiscreteAdaBoost && o.Kind != RealAdaBoost {
+		panic(fmt.Sprintf("Unknown AdaBoost kind: %d", o.Kind))
+	}
+	if o.Rounds <= 0 {
+		panic(fmt.Sprintf("Bad number of AdaBoost rounds: %d", o.Rounds))
+	}
+
+	c := &AdaBoostClassifier{kind: o.Kind}
+	weights := v.Zeroes(len(features))
+	for i := range weights {
+		weights[i] = 1 / float64(len(features))
+	}
+	// Boosted score of every training example.
+	scores := v.Zeroes(len(features))
+
+	for round := 0; round < o.Rounds; round++ {
+		classifier := weak(features, labels, weights.Copy())
+
+		votes := v.Zeroes(len(features))
+		weakError := 0.0
+		for i, f := range features {
+			votes[i] = c.weakVote(classifier, f)
+			if (votes[i] >= 0) != labels[i] {
+				weakError += weights[i]
+			}
+		}
+
+		alpha := 1.0
+		if o.Kind == DiscreteAdaBoost {
+			if weakError >= 0.5 {
+				if round > 0 {
+					break
+				}
+				alpha = 0
+				c.prior = classPrior(labels)
+				for i := range scores {
+					scores[i] = c.prior
+				}
+			} else {
+				alpha = 0.5 * math.Log((1-weakError)/math.Max(weakError, adaBoostEps))
+			}
+		}
+
+		c.classifiers = append(c.classifiers, classifier)
+		c.alphas = append(c.alphas, alpha)
+		c.weakErrors = append(c.weakErrors, weakError)
+
+		errors := 0
+		for i := range weights {
+			y := -1.0
+			if labels[i] {
+				y = 1
+			}
+			scores[i] += alpha * votes[i]
+			if (scores[i] >= 0) != labels[i] {
+				errors++
+			}
+			weights[i] *= math.Exp(-alpha * y * votes[i])
+		}
+		c.trainingErrors = append(c.trainingErrors, float64(errors)/float64(len(features)))
+
+		if o.Kind == DiscreteAdaBoost && (weakError == 0 || alpha == 0) {
+			break
+		}
+		normalize(weights)
+	}
+
+	return c
+}
+
+// Half the log-odds of true in labels, clipped like weak classifier
+// probabilities.
+func classPrior(labels []bool) float64 {
+	positive := 0
+	for _, l := range labels {
+		if l {
+			positive++
+		}
+	}
+	p := math.Min(math.Max(float64(positive)/float64(len(labels)), adaBoostEps), 1-adaBoostEps)
+	return 0.5 * math.Log(p/(1-p))
+}
+
+func NewAdaBoostTrainer(weak WeightedBinaryClassifierTrainer, o AdaBoostOptions) BinaryClassifierTrainer {
+	return func(features []v.F64, labels []bool) BinaryClassifier {
+		return TrainAdaBoostClassifier(features, labels, weak, o)
+	}
+}
+
+// Decision tree trained with example weights.
+func NewWeightedDecisionTreeTrainer(o DecisionTreeOptions) WeightedBinaryClassifierTrainer {
+	return func(features []v.F64, labels []bool, weights v.F64) BinaryClassifier {
+		tree := trainWeightedDecisionTree(features, binaryToNominal(labels), 2, weights, o, 0, nil)
+		return &binaryFromNominalClassifier{classifier: tree}
+	}
+}
+
+// Decision tree with a single split, the usual AdaBoost weak learner.
+func NewDecisionStumpTrainer(criterion SplitCriterion) WeightedBinaryClassifierTrainer {
+	return NewWeightedDecisionTreeTrainer(DecisionTreeOptions{Criterion: criterion, MaxDepth: 1})
+}
+
+// Vote of a weak classifier, positive for true.
+func (c *AdaBoostClassifier) weakVote(classifier BinaryClassifier, features v.F64) float64 {
+	if c.kind == DiscreteAdaBoost {
+		if result, _ := classifier.Classify(features); result {
+			return 1
+		}
+		return -1
+	}
+
+	p := math.Min(math.Max(positiveProbability(classifier, features), adaBoostEps), 1-adaBoostEps)
+	return 0.5 * math.Log(p/(1-p))
+}
+
+// Weighted sum of weak classifier votes.
+func (c *AdaBoostClassifier) Score(features v.F64) float64 {
+	score := c.prior
+	for t, classifier := range c.classifiers {
+		score += c.alphas[t] * c.weakVote(classifier, features)
+	}
+	return score
+}
+
+// Weights of the weak classifiers, one per round.
+func (c *AdaBoostClassifier) Alphas() v.F64 {
+	return c.alphas.Copy()
+}
+
+// Weighted training error of every weak classifier.
+func (c *AdaBoostClassifier) WeakErrors() v.F64 {
+	return c.weakErrors.Copy()
+}
+
+// Training set error rate of the boosted classifier after every round.
+func (c *AdaBoostClassifier) TrainingErrors() v.F64 {
+	return c.trainingErrors.Copy()
+}
+
+// P(true) = sigmoid(2 * score), the estimate AdaBoost minimizing
+// exponential loss converges to.
+func (c *AdaBoostClassifier) Probabilities(features v.F64) v.F64 {
+	p := sigmoid(2 * c.Score(features))
+	return v.F64{1 - p, p}
+}
+
+func (c *AdaBoostClassifier) LogProbabilities(features v.F64) v.F64 {
+	score := 2 * c.Score(features)
+	return v.F64{logSigmoid(-score), logSigmoid(score)}
+}
+
+// Confidence follows logistic regression: |0.5-P(true)|*2.
+func (c *AdaBoostClassifier) Classify(features v.F64) (bool, float64) {
+	score := c.Score(features)
+	return score >= 0, math.Abs(0.5-sigmoid(2*score)) * 2
+}
+
+const adaBoostModelType = "ai.adaBoost"
+
+type adaBoostState struct {
+	Kind           AdaBoostKind
+	Prior          float64
+	Alphas         v.F64
+	WeakErrors     v.F64
+	TrainingErrors v.F64
+	Classifiers    []json.RawMessage
+}
+
+func (c *AdaBoostClassifier) ModelType() string { return adaBoostModelType }
+
+func (c *AdaBoostClassifier) FeatureDim() int {
+	if len(c.classifiers) == 0 {
+		return 0
+	}
+	return featureDim(c.classifiers[0])
+}
+
+func (c *AdaBoostClassifier) MarshalModel(e Encoding) ([]byte, error) {
+	s := adaBoostState{Kind: c.kind, Prior: c.prior, Alphas: c.alphas, WeakErrors: c.weakErrors, TrainingErrors: c.trainingErrors}
+	for _, classifier := range c.classifiers {
+		m, err := asModel(classifier)
+		if err != nil {
+			return nil, err
+		}
+		data, err := EncodeModel(m, e)
+		if err != nil {
+			return nil, err
+		}
+		s.Classifiers = append(s.Classifiers, data)
+	}
+	return e.Marshal(s)
+}
+
+func loadAdaBoost(e Encoding, data []byte) (Model, error) {
+	var s adaBoostState
+	if err := e.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if len(s.Classifiers) == 0 {
+		return nil, errEmptyModel
+	}
+	if len(s.Alphas) != len(s.Classifiers) {
+		return nil, fmt.Errorf("%d alphas for %d classifiers", len(s.Alphas), len(s.Classifiers))
+	}
+
+	c := &AdaBoostClassifier{kind: s.Kind, prior: s.Prior, alphas: s.Alphas, weakErrors: s.WeakErrors, trainingErrors: s.TrainingErrors}
+	dim := -1
+	for _, data := range s.Classifiers {
+		classifier, err := decodeBinaryClassifier(data, e, &dim)
+		if err != nil {
+			return nil, err
+		}
+		c.classifiers = append(c.classifiers, classifier)
+	}
+	return c, nil
+}
+
+func init() {
+	RegisterModel(adaBoostModelType, loadAdaBoost)
+}
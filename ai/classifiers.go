@@ -1,7 +1,10 @@
 package ai
 
 import (
+	"encoding/json"
+	"fmt"
 	v "github.com/deboshire/exp/math/vector"
+	"math"
 	"math/rand"
 )
 
@@ -13,14 +16,50 @@ type BinaryClassifier interface {
 
 type BinaryClassifierTrainer func(features []v.F64, labels []bool) BinaryClassifier
 
+// Trainer that takes all its randomness from r, so that it can be run
+// concurrently and still give reproducible results.
+type RandBinaryClassifierTrainer func(features []v.F64, labels []bool, r *rand.Rand) BinaryClassifier
+
+// Binds the trainer to r. Not safe for concurrent use unless r is.
+func (t RandBinaryClassifierTrainer) WithRand(r *rand.Rand) BinaryClassifierTrainer {
+	return func(features []v.F64, labels []bool) BinaryClassifier {
+		return t(features, labels, r)
+	}
+}
+
+// Classifies given features into one of labelsCardinality classes.
+// 0 <= confidence <= 1 - classifier confidence of its result.
 type NominalClassifier interface {
 	Classify(features v.F64) (result int, confidence float64)
 }
 
+// Labels are in [0, labelsCardinality).
+type NominalClassifierTrainer func(features []v.F64, labels []int, labelsCardinality int) NominalClassifier
+
+// Classifier that estimates the full distribution over classes.
+// Binary classifiers return two values: P(false) and P(true).
+type ProbabilisticClassifier interface {
+	Probabilities(features v.F64) v.F64
+	LogProbabilities(features v.F64) v.F64
+}
+
+// One-vs-rest classifier: classifiers[i] separates class i from all others.
 type compoundNominalClassifier struct {
 	classifiers []BinaryClassifier
 }
 
+// One-vs-one classifier: every pair of classes has its own classifier.
+type oneVsOneNominalClassifier struct {
+	labelsCardinality int
+	pairs             []classifierPair
+}
+
+// Classifier answering true for class a and false for class b.
+type classifierPair struct {
+	a, b       int
+	classifier BinaryClassifier
+}
+
 // Evaluate binary classifier on a given data.
 // Returns percentage of correct hits.
 func EvaluateBinaryClassifier(c BinaryClassifier, features []v.F64, labels v.B) float64 {
@@ -41,10 +80,133 @@ func EvaluateBinaryClassifier(c BinaryClassifier, features []v.F64, labels v.B)
 	return float64(successes) / float64(len(features))
 }
 
+// Fraction of correctly classified examples.
+func nominalAccuracy(c NominalClassifier, features []v.F64, labels []int) float64 {
+	if len(features) == 0 {
+		return 0
+	}
+
+	successes := 0
+	for i, feature := range features {
+		if l, _ := c.Classify(feature); l == labels[i] {
+			successes++
+		}
+	}
+	return float64(successes) / float64(len(features))
+}
+
+// Converts binary classifier output into an estimate of P(true).
+// For logistic regression this recovers the sigmoid value.
+func positiveScore(result bool, confidence float64) float64 {
+	if result {
+		return 0.5 + confidence/2
+	}
+	return 0.5 - confidence/2
+}
+
+// Estimate of P(true), exact for probabilistic classifiers.
+func positiveProbability(c BinaryClassifier, features v.F64) float64 {
+	if p, ok := c.(ProbabilisticClassifier); ok {
+		return p.Probabilities(features)[1]
+	}
+	return positiveScore(c.Classify(features))
+}
+
+// Replaces p with p / sum(p). Zero vectors become the uniform distribution.
+func normalize(p v.F64) {
+	total := p.Sum()
+	if total == 0 {
+		for i := range p {
+			p[i] = 1 / float64(len(p))
+		}
+		return
+	}
+	p.Mul(1 / total)
+}
+
+func logs(p v.F64) v.F64 {
+	for i, x := range p {
+		p[i] = math.Log(x)
+	}
+	return p
+}
+
+// Positive class probabilities of all classifiers normalized to sum to 1.
+func (c *compoundNominalClassifier) Probabilities(features v.F64) v.F64 {
+	p := v.Zeroes(len(c.classifiers))
+	for i, classifier := range c.classifiers {
+		p[i] = positiveProbability(classifier, features)
+	}
+	normalize(p)
+	return p
+}
+
+func (c *compoundNominalClassifier) LogProbabilities(features v.F64) v.F64 {
+	return logs(c.Probabilities(features))
+}
+
+// Picks the class with the highest positive probability. Confidence is the
+// probability of the winner normalized over all classes.
 func (c *compoundNominalClassifier) Classify(features v.F64) (result int, confidence float64) {
-	panic("not implemented")
+	p := c.Probabilities(features)
+	for i := range p {
+		if p[i] > p[result] {
+			result = i
+		}
+	}
+	return result, p[result]
+}
+
+// Each pair contributes P(a) to class a and 1-P(a) to class b, and the
+// sums are normalized.
+func (c *oneVsOneNominalClassifier) Probabilities(features v.F64) v.F64 {
+	p, _ := c.vote(features)
+	return p
+}
+
+func (c *oneVsOneNominalClassifier) LogProbabilities(features v.F64) v.F64 {
+	return logs(c.Probabilities(features))
 }
 
+func (c *oneVsOneNominalClassifier) vote(features v.F64) (sums v.F64, votes []int) {
+	votes = make([]int, c.labelsCardinality)
+	sums = v.Zeroes(c.labelsCardinality)
+
+	for _, pair := range c.pairs {
+		score := positiveProbability(pair.classifier, features)
+		if score >= 0.5 {
+			votes[pair.a]++
+		} else {
+			votes[pair.b]++
+		}
+		sums[pair.a] += score
+		sums[pair.b] += 1 - score
+	}
+
+	normalize(sums)
+	return
+}
+
+// Every pairwise classifier votes for one class. Ties are broken by the
+// confidence summed over all pairs, which is also normalized into the
+// returned confidence.
+func (c *oneVsOneNominalClassifier) Classify(features v.F64) (result int, confidence float64) {
+	sums, votes := c.vote(features)
+
+	for i := 1; i < c.labelsCardinality; i++ {
+		if votes[i] > votes[result] || (votes[i] == votes[result] && sums[i] > sums[result]) {
+			result = i
+		}
+	}
+
+	return result, sums[result]
+}
+
+// Trains one classifier per class, one after another. Trainers such as
+// NewLogisticRegressionTrainer share one sgrad.TermCrit between all their
+// calls, and stateful criteria are not safe for concurrent use, so
+// concurrency is opt-in: use TrainNominalClassifierFromBinaryParallel with
+// a trainer that keeps no shared state.
 func TrainNominalClassifierFromBinary(
 	features []v.F64,
 	labels []int,
@@ -52,20 +214,120 @@ func TrainNominalClassifierFromBinary(
 	binaryTrainer BinaryClassifierTrainer) NominalClassifier {
 
 	classifiers := make([]BinaryClassifier, labelsCardinality)
-
 	// TODO(mike): parallelize
-	for i := 0; i < labelsCardinality; i++ {
-		boolLabels := make([]bool, len(labels))
-		for j, l := range labels {
-			boolLabels[j] = l == i
-		}
-
-		classifiers[i] = binaryTrainer(features, boolLabels)
+	for i := range classifiers {
+		classifiers[i] = binaryTrainer(features, oneVsRest(labels, i))
 	}
 
 	return &compoundNominalClassifier{classifiers: classifiers}
 }
 
+// True for examples of class i, false for all others.
+func oneVsRest(labels []int, i int) []bool {
+	result := make([]bool, len(labels))
+	for j, l := range labels {
+		result[j] = l == i
+	}
+	return result
+}
+
+// Trains one-vs-rest classifiers for all classes with at most concurrency
+// trainers running at once (GOMAXPROCS if concurrency <= 0).
+// Every trainer gets its own random source seeded from r (global source if
+// nil), so a seeded r gives the same classifier regardless of concurrency.
+// Trainers report failures only by panicking; a panic in any trainer is
+// returned as *TrainingError with the class index.
+func TrainNominalClassifierFromBinaryParallel(
+	features []v.F64,
+	labels []int,
+	labelsCardinality int,
+	binaryTrainer RandBinaryClassifierTrainer,
+	concurrency int,
+	r *rand.Rand) (NominalClassifier, error) {
+
+	classifiers := make([]BinaryClassifier, labelsCardinality)
+
+	err := runConcurrently(labelsCardinality, concurrency, r, func(i int, r *rand.Rand) {
+		classifiers[i] = binaryTrainer(features, oneVsRest(labels, i), r)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &compoundNominalClassifier{classifiers: classifiers}, nil
+}
+
+// Nominal classifier with two classes used as a binary one, class 1 is true.
+type binaryFromNominalClassifier struct {
+	classifier NominalClassifier
+}
+
+// Trains nominal classifiers with two classes, false being class 0 and true
+// class 1.
+func NewBinaryTrainerFromNominal(trainer NominalClassifierTrainer) BinaryClassifierTrainer {
+	return func(features []v.F64, labels []bool) BinaryClassifier {
+		return &binaryFromNominalClassifier{classifier: trainer(features, binaryToNominal(labels), 2)}
+	}
+}
+
+func (c *binaryFromNominalClassifier) Probabilities(features v.F64) v.F64 {
+	if p, ok := c.classifier.(ProbabilisticClassifier); ok {
+		return p.Probabilities(features)
+	}
+
+	result, confidence := c.classifier.Classify(features)
+	if result == 1 {
+		return v.F64{1 - confidence, confidence}
+	}
+	return v.F64{confidence, 1 - confidence}
+}
+
+func (c *binaryFromNominalClassifier) LogProbabilities(features v.F64) v.F64 {
+	if p, ok := c.classifier.(ProbabilisticClassifier); ok {
+		return p.LogProbabilities(features)
+	}
+	return logs(c.Probabilities(features))
+}
+
+// Confidence follows logistic regression: |0.5-P(true)|*2.
+func (c *binaryFromNominalClassifier) Classify(features v.F64) (bool, float64) {
+	p := c.Probabilities(features)[1]
+	return p >= 0.5, math.Abs(0.5-p) * 2
+}
+
+// Trains labelsCardinality*(labelsCardinality-1)/2 classifiers, one for
+// every pair of classes, each on the examples of its two classes only.
+// Pairs without any training examples are skipped.
+func TrainNominalClassifierOneVsOne(
+	features []v.F64,
+	labels []int,
+	labelsCardinality int,
+	binaryTrainer BinaryClassifierTrainer) NominalClassifier {
+
+	var pairs []classifierPair
+
+	for a := 0; a < labelsCardinality; a++ {
+		for b := a + 1; b < labelsCardinality; b++ {
+			var pairFeatures []v.F64
+			var pairLabels []bool
+			for j, l := range labels {
+				if l == a || l == b {
+					pairFeatures = append(pairFeatures, features[j])
+					pairLabels = append(pairLabels, l == a)
+				}
+			}
+
+			if len(pairFeatures) == 0 {
+				continue
+			}
+
+			pairs = append(pairs, classifierPair{a: a, b: b, classifier: binaryTrainer(pairFeatures, pairLabels)})
+		}
+	}
+
+	return &oneVsOneNominalClassifier{labelsCardinality: labelsCardinality, pairs: pairs}
+}
+
 func shuffleFeaturesAndLabels(features []v.F64, labels v.B) {
 	for i := len(features) - 1; i > 0; i-- {
 		j := rand.Intn(i)
@@ -89,3 +351,181 @@ func HoldoutTestBinaryClassifier(features []v.F64, labels v.B, testingFraction f
 
 	return EvaluateBinaryClassifier(classifier, testingFeatures, testingLabels)
 }
+
+const (
+	oneVsRestModelType         = "ai.oneVsRest"
+	oneVsOneModelType          = "ai.oneVsOne"
+	binaryFromNominalModelType = "ai.binaryFromNominal"
+)
+
+type oneVsRestState struct {
+	Classifiers []json.RawMessage
+}
+
+type oneVsOneState struct {
+	LabelsCardinality int
+	Pairs             []classifierPairState
+}
+
+type classifierPairState struct {
+	A, B       int
+	Classifier json.RawMessage
+}
+
+func featureDim(c interface{}) int {
+	if m, ok := c.(Model); ok {
+		return m.FeatureDim()
+	}
+	return 0
+}
+
+func (c *compoundNominalClassifier) ModelType() string { return oneVsRestModelType }
+
+func (c *compoundNominalClassifier) FeatureDim() int {
+	if len(c.classifiers) == 0 {
+		return 0
+	}
+	return featureDim(c.classifiers[0])
+}
+
+func (c *compoundNominalClassifier) MarshalModel(e Encoding) ([]byte, error) {
+	var s oneVsRestState
+	for _, classifier := range c.classifiers {
+		m, err := asModel(classifier)
+		if err != nil {
+			return nil, err
+		}
+		data, err := EncodeModel(m, e)
+		if err != nil {
+			return nil, err
+		}
+		s.Classifiers = append(s.Classifiers, data)
+	}
+	return e.Marshal(s)
+}
+
+// Decodes a member of a compound model. dim is set from the first member
+// if it is negative, later members must match it.
+func decodeMember(data []byte, e Encoding, dim *int) (Model, error) {
+	var m Model
+	var err error
+	if *dim < 0 {
+		m, err = DecodeModel(data, e)
+	} else {
+		m, err = decodeNestedModel(data, e, *dim)
+	}
+	if err != nil {
+		return nil, err
+	}
+	*dim = m.FeatureDim()
+	return m, nil
+}
+
+// Decodes a binary classifier of a compound model, see decodeMember.
+func decodeBinaryClassifier(data []byte, e Encoding, dim *int) (BinaryClassifier, error) {
+	m, err := decodeMember(data, e, dim)
+	if err != nil {
+		return nil, err
+	}
+
+	classifier, ok := m.(BinaryClassifier)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a binary classifier", m.ModelType())
+	}
+	return classifier, nil
+}
+
+func loadOneVsRest(e Encoding, data []byte) (Model, error) {
+	var s oneVsRestState
+	if err := e.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if len(s.Classifiers) == 0 {
+		return nil, errEmptyModel
+	}
+
+	c := &compoundNominalClassifier{classifiers: make([]BinaryClassifier, len(s.Classifiers))}
+	dim := -1
+	for i, data := range s.Classifiers {
+		var err error
+		if c.classifiers[i], err = decodeBinaryClassifier(data, e, &dim); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *oneVsOneNominalClassifier) ModelType() string { return oneVsOneModelType }
+
+func (c *oneVsOneNominalClassifier) FeatureDim() int {
+	if len(c.pairs) == 0 {
+		return 0
+	}
+	return featureDim(c.pairs[0].classifier)
+}
+
+func (c *oneVsOneNominalClassifier) MarshalModel(e Encoding) ([]byte, error) {
+	s := oneVsOneState{LabelsCardinality: c.labelsCardinality}
+	for _, pair := range c.pairs {
+		m, err := asModel(pair.classifier)
+		if err != nil {
+			return nil, err
+		}
+		data, err := EncodeModel(m, e)
+		if err != nil {
+			return nil, err
+		}
+		s.Pairs = append(s.Pairs, classifierPairState{A: pair.a, B: pair.b, Classifier: data})
+	}
+	return e.Marshal(s)
+}
+
+func loadOneVsOne(e Encoding, data []byte) (Model, error) {
+	var s oneVsOneState
+	if err := e.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+
+	c := &oneVsOneNominalClassifier{labelsCardinality: s.LabelsCardinality}
+	dim := -1
+	for _, pair := range s.Pairs {
+		if pair.A < 0 || pair.B < 0 || pair.A >= s.LabelsCardinality || pair.B >= s.LabelsCardinality {
+			return nil, fmt.Errorf("Bad pair %d, %d for %d classes", pair.A, pair.B, s.LabelsCardinality)
+		}
+		classifier, err := decodeBinaryClassifier(pair.Classifier, e, &dim)
+		if err != nil {
+			return nil, err
+		}
+		c.pairs = append(c.pairs, classifierPair{a: pair.A, b: pair.B, classifier: classifier})
+	}
+	return c, nil
+}
+
+func (c *binaryFromNominalClassifier) ModelType() string { return binaryFromNominalModelType }
+func (c *binaryFromNominalClassifier) FeatureDim() int   { return featureDim(c.classifier) }
+
+func (c *binaryFromNominalClassifier) MarshalModel(e Encoding) ([]byte, error) {
+	m, err := asModel(c.classifier)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeModel(m, e)
+}
+
+func loadBinaryFromNominal(e Encoding, data []byte) (Model, error) {
+	m, err := DecodeModel(data, e)
+	if err != nil {
+		return nil, err
+	}
+	classifier, ok := m.(NominalClassifier)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a nominal classifier", m.ModelType())
+	}
+	return &binaryFromNominalClassifier{classifier: classifier}, nil
+}
+
+func init() {
+	RegisterModel(oneVsRestModelType, loadOneVsRest)
+	RegisterModel(oneVsOneModelType, loadOneVsOne)
+	RegisterModel(binaryFromNominalModelType, loadBinaryFromNominal)
+}
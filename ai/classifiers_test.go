@@ -0,0 +1,601 @@
+package ai
+
+import (
+	"github.com/deboshire/exp/math/opt/sgrad"
+	v "github.com/deboshire/exp/math/vector"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Three well separated clusters on a plane, with a bias term.
+func clusters(n int) (features []v.F64, labels []int) {
+	centers := []v.F64{{0, 0}, {4, 0}, {0, 4}}
+	for i := 0; i < n; i++ {
+		l := i % len(centers)
+		c := centers[l]
+		features = append(features, v.F64{1, c[0] + rand.Float64() - 0.5, c[1] + rand.Float64() - 0.5})
+		labels = append(labels, l)
+	}
+	return
+}
+
+func testTrainer() BinaryClassifierTrainer {
+	return NewLogisticRegressionTrainer(0, &sgrad.NumIterationsCrit{NumIterations: 50}, 1e-8)
+}
+
+func TestOneVsRest(t *testing.T) {
+	features, labels := clusters(150)
+	c := TrainNominalClassifierFromBinary(features, labels, 3, testTrainer())
+
+	if a := nominalAccuracy(c, features, labels); a < 0.95 {
+		t.Error("Bad accuracy:", a)
+	}
+
+	if _, confidence := c.Classify(v.F64{1, 4, 0}); confidence <= 0 || confidence > 1 {
+		t.Error("Bad confidence:", confidence)
+	}
+}
+
+func TestOneVsOne(t *testing.T) {
+	features, labels := clusters(150)
+	c := TrainNominalClassifierOneVsOne(features, labels, 3, testTrainer())
+
+	if a := nominalAccuracy(c, features, labels); a < 0.95 {
+		t.Error("Bad accuracy:", a)
+	}
+
+	if l, _ := c.Classify(v.F64{1, 0, 4}); l != 2 {
+		t.Error("Bad class:", l)
+	}
+}
+
+func TestSoftmaxRegression(t *testing.T) {
+	features, labels := clusters(150)
+	c := TrainSoftmaxRegressionClassifier(features, labels, 3, 0.001, &sgrad.NumIterationsCrit{NumIterations: 50}, 1e-8)
+
+	if a := nominalAccuracy(c, features, labels); a < 0.95 {
+		t.Error("Bad accuracy:", a)
+	}
+
+	p := c.(ProbabilisticClassifier).Probabilities(v.F64{1, 4, 0})
+	sum := p[0] + p[1] + p[2]
+	if p[1] < 0.5 || sum < 1-1e-9 || sum > 1+1e-9 {
+		t.Error("Bad probabilities:", p)
+	}
+}
+
+func TestSparseLogisticRegression(t *testing.T) {
+	// Bags of 3 words out of 200, true if any word is below 20.
+	r := rand.New(rand.NewSource(1))
+	var sparse []v.Sparse
+	var dense []v.F64
+	var labels v.B
+	for i := 0; i < 500; i++ {
+		indices := []int{0}
+		values := v.F64{1}
+		label := false
+		for _, w := range r.Perm(200)[:3] {
+			indices = append(indices, w+1)
+			values = append(values, 1)
+			label = label || w < 20
+		}
+		s := v.NewSparse(201, indices, values)
+		sparse = append(sparse, s)
+		dense = append(dense, s.ToDense())
+		labels = append(labels, label)
+	}
+
+	crit := &sgrad.NumIterationsCrit{NumIterations: 20}
+	c := TrainSparseLogisticRegressionClassifier(sparse, labels, 0, crit, 1e-8, rand.New(rand.NewSource(2)))
+	d := TrainLogisticRegressionClassifierWithRand(dense, labels, 0, crit, 1e-8, rand.New(rand.NewSource(2)))
+
+	if a := EvaluateBinaryClassifier(c, dense, labels); a < 0.95 {
+		t.Error("Bad accuracy:", a)
+	}
+	if !c.(*logisticRegressionClassifier).theta.Eq(d.(*logisticRegressionClassifier).theta, 1e-12) {
+		t.Error("Sparse and dense training differ")
+	}
+	reg := TrainSparseLogisticRegressionClassifier(sparse, labels, 0.001, crit, 1e-8, rand.New(rand.NewSource(2)))
+	if a := EvaluateBinaryClassifier(reg, dense, labels); a < 0.95 {
+		t.Error("Bad regularized accuracy:", a)
+	}
+	if n, n0 := reg.(*logisticRegressionClassifier).theta[1:].Norm2(), c.(*logisticRegressionClassifier).theta[1:].Norm2(); n >= n0 {
+		t.Error("Regularization does not shrink weights:", n, n0)
+	}
+
+	for i, f := range sparse {
+		l1, c1 := c.ClassifySparse(f)
+		l2, c2 := c.Classify(dense[i])
+		if l1 != l2 || math.Abs(c1-c2) > 1e-12 {
+			t.Error("Sparse and dense classification differ for", i)
+		}
+	}
+}
+
+func TestLogisticRegressionF32(t *testing.T) {
+	features, classes := clusters(150)
+	var single []v.F32
+	var labels v.B
+	for i, f := range features {
+		single = append(single, f.ToF32())
+		labels = append(labels, classes[i] == 1)
+	}
+
+	crit := &sgrad.NumIterationsCrit{NumIterations: 50}
+	c := TrainLogisticRegressionClassifierF32(single, labels, 0.001, crit, 1e-8, rand.New(rand.NewSource(2)))
+	d := TrainLogisticRegressionClassifierWithRand(features, labels, 0.001, crit, 1e-8, rand.New(rand.NewSource(2)))
+
+	if a := EvaluateBinaryClassifier(c, features, labels); a < 0.95 {
+		t.Error("Bad accuracy:", a)
+	}
+	for i, f := range single {
+		l1, c1 := c.ClassifyF32(f)
+		if l2, c2 := c.Classify(features[i]); l1 != l2 || math.Abs(c1-c2) > 1e-6 {
+			t.Error("Single and double precision classification differ:", l1, c1, l2, c2)
+		}
+	}
+	if theta, theta64 := c.(*logisticRegressionClassifier).theta, d.(*logisticRegressionClassifier).theta; !theta.Eq(theta64, 1e-5) {
+		t.Error("Single and double precision training differ:", theta, theta64)
+	}
+}
+
+func TestProbabilities(t *testing.T) {
+	features, labels := clusters(150)
+	trainer := testTrainer()
+	classifiers := []NominalClassifier{
+		TrainNominalClassifierFromBinary(features, labels, 3, trainer),
+		TrainNominalClassifierOneVsOne(features, labels, 3, trainer),
+		TrainSoftmaxRegressionClassifier(features, labels, 3, 0, &sgrad.NumIterationsCrit{NumIterations: 10}, 1e-8),
+	}
+
+	for i, c := range classifiers {
+		x := v.F64{1, 0, 4}
+		p := c.(ProbabilisticClassifier).Probabilities(x)
+		logp := c.(ProbabilisticClassifier).LogProbabilities(x)
+		l, confidence := c.Classify(x)
+		if p[0]+p[1]+p[2] < 1-1e-9 || p[0]+p[1]+p[2] > 1+1e-9 || p[l] != confidence {
+			t.Error(i, "Bad probabilities:", p, "confidence:", confidence)
+		}
+		for k := range p {
+			if math.Abs(math.Exp(logp[k])-p[k]) > 1e-9 {
+				t.Error(i, "Bad log probabilities:", logp, p)
+			}
+		}
+	}
+
+	lr := trainer(features[:3], []bool{true, false, false}).(ProbabilisticClassifier)
+	logp := lr.LogProbabilities(v.F64{1, 1e3, 1e3})
+	if math.IsInf(logp[0], 0) || math.IsInf(logp[1], 0) {
+		t.Error("Log probabilities overflow:", logp)
+	}
+}
+
+func TestNaiveBayes(t *testing.T) {
+	features, labels := clusters(150)
+	counts := make([]v.F64, len(features))
+	for i, f := range features {
+		counts[i] = v.F64{float64(10 * (labels[i] + 1)), f[1] + 1, f[2] + 1}
+	}
+
+	for kind, data := range map[NaiveBayesKind][]v.F64{GaussianNaiveBayes: features, MultinomialNaiveBayes: counts} {
+		c := TrainNaiveBayesClassifier(data, labels, 3, kind, 1e-2)
+		if a := nominalAccuracy(c, data, labels); a < 0.95 {
+			t.Error(kind, "Bad accuracy:", a)
+		}
+	}
+
+	c := TrainNaiveBayesClassifier([]v.F64{{1}, {1}, {0}}, []int{0, 0, 1}, 2, BernoulliNaiveBayes, 1)
+	if p := c.(ProbabilisticClassifier).Probabilities(v.F64{1}); math.Abs(p[0]-9.0/11) > 1e-12 {
+		t.Error("Bad Bernoulli probabilities:", p)
+	}
+
+	binary := NewNaiveBayesBinaryTrainer(BernoulliNaiveBayes, 1)([]v.F64{{1}, {1}, {0}}, v.B{false, false, true})
+	if l, confidence := binary.Classify(v.F64{1}); l || math.Abs(confidence-7.0/11) > 1e-12 {
+		t.Error("Bad binary result:", l, confidence)
+	}
+
+	// Class 2 has no examples.
+	c = TrainNaiveBayesClassifier([]v.F64{{2, 0}, {0, 0}}, []int{0, 1}, 3, MultinomialNaiveBayes, 1)
+	for _, p := range c.(ProbabilisticClassifier).Probabilities(v.F64{1, 1}) {
+		if math.IsNaN(p) {
+			t.Error("Bad probabilities with an empty class:", p)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("No panic without smoothing")
+		}
+	}()
+	TrainNaiveBayesClassifier([]v.F64{{1}, {0}}, []int{0, 1}, 2, BernoulliNaiveBayes, 0)
+}
+
+func TestNeighbourIndexes(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, dim := range []int{2, 40} {
+		var features []v.F64
+		for i := 0; i < 500; i++ {
+			f := v.Zeroes(dim)
+			for j := range f {
+				// Coarse grid to have plenty of equal distances.
+				f[j] = float64(r.Intn(5))
+			}
+			features = append(features, f)
+		}
+
+		brute := newNeighbourFinder(features, BruteForceIndex)
+		for _, index := range []NeighbourIndex{AutoIndex, KDTreeIndex, BallTreeIndex} {
+			finder := newNeighbourFinder(features, index)
+			for q := 0; q < 20; q++ {
+				query := features[r.Intn(len(features))].Copy()
+				query[0] += 0.5
+				want := brute.nearest(query, 7)
+				got := finder.nearest(query, 7)
+				if len(got) != len(want) {
+					t.Fatal(index, dim, "got:", got, "want:", want)
+				}
+				for i := range want {
+					if got[i] != want[i] {
+						t.Fatal(index, dim, "got:", got, "want:", want)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestKNN(t *testing.T) {
+	features, labels := clusters(150)
+	for _, weighted := range []bool{false, true} {
+		c := TrainKNNClassifier(features, labels, 3, 5, weighted, AutoIndex)
+		if a := nominalAccuracy(c, features, labels); a < 0.95 {
+			t.Error(weighted, "Bad accuracy:", a)
+		}
+	}
+
+	c := TrainKNNClassifier([]v.F64{{0}, {1}, {3}}, []int{0, 1, 1}, 2, 3, false, AutoIndex)
+	if l, confidence := c.Classify(v.F64{0}); l != 1 || math.Abs(confidence-2.0/3) > 1e-12 {
+		t.Error("Bad unweighted result:", l, confidence)
+	}
+
+	c = TrainKNNClassifier([]v.F64{{0}, {1}, {3}}, []int{0, 1, 1}, 2, 3, true, AutoIndex)
+	if p := c.(ProbabilisticClassifier).Probabilities(v.F64{0.5}); math.Abs(p[0]-5.0/11) > 1e-12 {
+		t.Error("Bad weighted probabilities:", p)
+	}
+	if l, confidence := c.Classify(v.F64{0}); l != 0 || confidence != 1 {
+		t.Error("Exact match does not win:", l, confidence)
+	}
+}
+
+func TestDecisionTree(t *testing.T) {
+	features, labels := clusters(150)
+	for _, criterion := range []SplitCriterion{GiniCriterion, EntropyCriterion} {
+		c := TrainDecisionTree(features, labels, 3, DecisionTreeOptions{Criterion: criterion})
+		if a := nominalAccuracy(c, features, labels); a != 1 {
+			t.Error(criterion, "Bad accuracy:", a)
+		}
+		if c.NumLeaves() != 3 || c.Depth() != 2 {
+			t.Errorf("%s: %d leaves, depth %d:\n%s", criterion, c.NumLeaves(), c.Depth(), c)
+		}
+	}
+
+	// x1 separates the classes except for one noisy example, which x0 marks.
+	features = []v.F64{{0, 1}, {0, 2}, {1, 3}, {0, 4}, {0, 5}, {0, 6}, {0, 7}, {0, 8}}
+	labels = []int{0, 0, 1, 0, 1, 1, 1, 1}
+	c := TrainDecisionTree(features, labels, 2, DecisionTreeOptions{})
+	if a := nominalAccuracy(c, features, labels); a != 1 {
+		t.Error("Bad accuracy:", a, "\n", c)
+	}
+
+	if c.NumLeaves() != 3 {
+		t.Error("Noisy example is not separated:\n", c)
+	}
+
+	want := "x1 <= 4.5\n" +
+		"  class 0 (0.750 of 4)\n" +
+		"x1 > 4.5\n" +
+		"  class 1 (1.000 of 4)\n"
+
+	for _, o := range []DecisionTreeOptions{{MaxDepth: 1}, {MinSamplesLeaf: 4}, {PruningAlpha: 0.2}} {
+		c := TrainDecisionTree(features, labels, 2, o)
+		if s := c.String(); s != want {
+			t.Errorf("%+v: got\n%s", o, s)
+		}
+	}
+
+	c = TrainDecisionTree(features, labels, 2, DecisionTreeOptions{PruningAlpha: 0.5})
+	if s := c.Format([]string{"a", "b"}, []string{"no", "yes"}); s != "yes (0.625 of 8)\n" {
+		t.Error("Bad pruned tree:", s)
+	}
+}
+
+func TestRandomForest(t *testing.T) {
+	features, labels := clusters(150)
+	// Feature 3 is noise.
+	for i := range features {
+		features[i] = append(features[i], rand.Float64())
+	}
+
+	o := RandomForestOptions{EnsembleOptions: EnsembleOptions{Size: 20}, MaxFeatures: 2}
+	c1, err := TrainRandomForest(features, labels, 3, o, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := nominalAccuracy(c1, features, labels); a < 0.95 {
+		t.Error("Bad accuracy:", a)
+	}
+	if a := c1.OOBAccuracy(); a < 0.9 || a > 1 {
+		t.Error("Bad out-of-bag accuracy:", a)
+	}
+
+	o.Concurrency = 1
+	c2, _ := TrainRandomForest(features, labels, 3, o, rand.New(rand.NewSource(1)))
+	for _, f := range features {
+		if p1, p2 := c1.Probabilities(f), c2.Probabilities(f); !p1.Eq(p2, 0) {
+			t.Fatal("Concurrency changes the forest:", p1, p2)
+		}
+	}
+
+	importance := PermutationImportance(c1, features, labels, 3, rand.New(rand.NewSource(1)))
+	if importance[1] < 0.2 || importance[2] < 0.2 || math.Abs(importance[0]) > 0 || math.Abs(importance[3]) > 0.05 {
+		t.Error("Bad importance:", importance)
+	}
+
+	for _, f := range []func(){
+		func() { PermutationImportance(c1, features, labels, 0, nil) },
+		func() { PermutationImportance(c1, nil, nil, 3, nil) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("No panic on bad permutation importance arguments")
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func TestBagging(t *testing.T) {
+	features, labels := clusters(150)
+	c, err := TrainBagging(features, labels, 3, NewKNNTrainer(1, false, AutoIndex), EnsembleOptions{Size: 5}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := nominalAccuracy(c, features, labels); a < 0.95 {
+		t.Error("Bad accuracy:", a)
+	}
+
+	_, err = TrainBagging(features, labels, 3, func([]v.F64, []int, int) NominalClassifier { panic("failed") }, EnsembleOptions{Size: 5}, nil)
+	if _, ok := err.(*TrainingError); !ok {
+		t.Error("Expected training error, got:", err)
+	}
+}
+
+// True inside an interval, which no single decision stump can learn.
+func interval(n int) (features []v.F64, labels v.B) {
+	for i := 0; i < n; i++ {
+		x := float64(i) / float64(n)
+		features = append(features, v.F64{x})
+		labels = append(labels, x > 0.3 && x < 0.7)
+	}
+	return
+}
+
+type constantClassifier bool
+
+func (c constantClassifier) Classify(features v.F64) (bool, float64) {
+	return bool(c), 1
+}
+
+func TestAdaBoost(t *testing.T) {
+	features, labels := interval(100)
+
+	for _, kind := range []AdaBoostKind{DiscreteAdaBoost, RealAdaBoost} {
+		c := TrainAdaBoostClassifier(features, labels, NewDecisionStumpTrainer(GiniCriterion), AdaBoostOptions{Kind: kind, Rounds: 20})
+		if a := EvaluateBinaryClassifier(c, features, labels); a != 1 {
+			t.Error(kind, "Bad accuracy:", a)
+		}
+
+		alphas, errors := c.Alphas(), c.TrainingErrors()
+		if len(alphas) != 20 || len(errors) != 20 || len(c.WeakErrors()) != 20 {
+			t.Error(kind, "Bad number of rounds:", alphas, errors)
+		}
+		if errors[0] != 0.3 || errors[19] != 0 {
+			t.Error(kind, "Bad training errors:", errors)
+		}
+		if kind == DiscreteAdaBoost && math.Abs(alphas[0]-0.5*math.Log(0.7/0.3)) > 1e-12 {
+			t.Error(kind, "Bad first alpha:", alphas[0])
+		}
+	}
+
+	c := TrainAdaBoostClassifier(features[:50], labels[:50], NewDecisionStumpTrainer(GiniCriterion), AdaBoostOptions{Rounds: 20})
+	if len(c.Alphas()) != 1 {
+		t.Error("Boosting does not stop after a perfect classifier:", c.Alphas())
+	}
+
+	// True for all examples, wrong on 61% of them.
+	c = TrainAdaBoostClassifier(features, labels, func(features []v.F64, labels []bool, weights v.F64) BinaryClassifier {
+		return constantClassifier(true)
+	}, AdaBoostOptions{Rounds: 20})
+	if alphas := c.Alphas(); len(alphas) != 1 || alphas[0] != 0 {
+		t.Error("Bad alphas without a useful weak classifier:", alphas)
+	}
+	if a := EvaluateBinaryClassifier(c, features, labels); a != 0.61 {
+		t.Error("Does not predict the majority class without a useful weak classifier:", a)
+	}
+}
+
+// Decision stump trained on negated labels, worse than chance.
+func negatedStumpTrainer(features []v.F64, labels []bool, weights v.F64) BinaryClassifier {
+	negated := make([]bool, len(labels))
+	for i, l := range labels {
+		negated[i] = !l
+	}
+	return NewDecisionStumpTrainer(GiniCriterion)(features, negated, weights)
+}
+
+func TestLinearSVM(t *testing.T) {
+	features, nominal := clusters(150)
+	labels := make(v.B, len(nominal))
+	for i, l := range nominal {
+		labels[i] = l == 1
+	}
+
+	for _, loss := range []SVMLoss{HingeLoss, SquaredHingeLoss} {
+		o := LinearSVMOptions{Lambda: 0.01, Loss: loss}
+		c := TrainLinearSVMClassifier(features, labels, o, &sgrad.NumIterationsCrit{NumIterations: 20}, 1e-8)
+		if a := EvaluateBinaryClassifier(c, features, labels); a < 0.95 {
+			t.Error(loss, "Bad accuracy:", a)
+		}
+		if _, ok := c.(ProbabilisticClassifier); ok {
+			t.Error(loss, "Probabilistic without Platt scaling")
+		}
+
+		o.PlattScaling = true
+		c = TrainLinearSVMClassifier(features, labels, o, &sgrad.NumIterationsCrit{NumIterations: 20}, 1e-8)
+		if a := EvaluateBinaryClassifier(c, features, labels); a < 0.95 {
+			t.Error(loss, "Bad accuracy with Platt scaling:", a)
+		}
+		p := c.(ProbabilisticClassifier).Probabilities(v.F64{1, 4, 0})
+		if p[1] < 0.9 || math.Abs(p[0]+p[1]-1) > 1e-12 {
+			t.Error(loss, "Bad probabilities:", p)
+		}
+	}
+}
+
+func TestPlattScaling(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	margins := v.Zeroes(10000)
+	labels := make(v.B, len(margins))
+	for i := range margins {
+		margins[i] = r.Float64()*6 - 3
+		labels[i] = r.Float64() < sigmoid(2*margins[i]-1)
+	}
+
+	if a, b := fitPlatt(margins, labels); math.Abs(a+2) > 0.2 || math.Abs(b-1) > 0.2 {
+		t.Error("Bad fit:", a, b)
+	}
+}
+
+func TestParallelIsReproducible(t *testing.T) {
+	features, labels := clusters(90)
+	trainer := NewLogisticRegressionRandTrainer(0, &sgrad.NumIterationsCrit{NumIterations: 5}, 1e-8)
+
+	c1, err := TrainNominalClassifierFromBinaryParallel(features, labels, 3, trainer, 1, rand.New(rand.NewSource(7)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := TrainNominalClassifierFromBinaryParallel(features, labels, 3, trainer, 3, rand.New(rand.NewSource(7)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		theta1 := c1.(*compoundNominalClassifier).classifiers[i].(*logisticRegressionClassifier).theta
+		theta2 := c2.(*compoundNominalClassifier).classifiers[i].(*logisticRegressionClassifier).theta
+		if !theta1.Eq(theta2, 0) {
+			t.Error("Classifier", i, "differs:", theta1, theta2)
+		}
+	}
+}
+
+func TestParallelPropagatesPanic(t *testing.T) {
+	features, labels := clusters(30)
+	trainer := func(features []v.F64, labels []bool, r *rand.Rand) BinaryClassifier {
+		if !labels[1] {
+			panic("boom")
+		}
+		return nil
+	}
+
+	_, err := TrainNominalClassifierFromBinaryParallel(features, labels, 3, trainer, 2, nil)
+	if e, ok := err.(*TrainingError); !ok || e.Task != 0 || e.Value != "boom" {
+		t.Error("Unexpected error:", err)
+	}
+}
+
+// Serial training must not share trainers between goroutines, nor wrap their
+// panics.
+func TestOneVsRestSerial(t *testing.T) {
+	features, labels := clusters(30)
+	var trained []int
+	trainer := func(features []v.F64, labels []bool) BinaryClassifier {
+		for i, l := range labels {
+			if l {
+				trained = append(trained, i)
+				break
+			}
+		}
+		return testTrainer()(features, labels)
+	}
+	TrainNominalClassifierFromBinary(features, labels, 3, trainer)
+	if len(trained) != 3 || trained[0] != 0 || trained[1] != 1 || trained[2] != 2 {
+		t.Error("Bad training order:", trained)
+	}
+
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Error("Unexpected panic:", p)
+		}
+	}()
+	TrainNominalClassifierFromBinary(features, labels, 3, func(features []v.F64, labels []bool) BinaryClassifier {
+		panic("boom")
+	})
+}
+
+func TestFolds(t *testing.T) {
+	labels := []int{0, 0, 0, 0, 0, 0, 1, 1, 1, 2, 2, 2}
+	r := rand.New(rand.NewSource(1))
+
+	for _, folds := range []Folds{KFolds(len(labels), 3, r), StratifiedKFolds(labels, 3, r), LeaveOneOutFolds(len(labels))} {
+		seen := make([]int, len(labels))
+		for _, fold := range folds {
+			for _, i := range fold {
+				seen[i]++
+			}
+		}
+		for i, s := range seen {
+			if s != 1 {
+				t.Error("Example", i, "is tested", s, "times in", folds)
+			}
+		}
+	}
+
+	for _, fold := range StratifiedKFolds(labels, 3, r) {
+		counts := make([]int, 3)
+		for _, i := range fold {
+			counts[labels[i]]++
+		}
+		if counts[0] != 2 || counts[1] != 1 || counts[2] != 1 {
+			t.Error("Fold is not stratified:", fold)
+		}
+	}
+}
+
+func TestCrossValidation(t *testing.T) {
+	features, labels := clusters(60)
+	original := append([]int(nil), labels...)
+
+	result := CrossValidateNominalClassifier(
+		features,
+		labels,
+		3,
+		StratifiedKFolds(labels, 5, rand.New(rand.NewSource(1))),
+		NewSoftmaxRegressionTrainer(0, &sgrad.NumIterationsCrit{NumIterations: 20}, 1e-8))
+
+	if len(result.Scores) != 5 || result.Mean < 0.9 || result.StdDev < 0 || result.StdDev > 0.1 {
+		t.Error("Bad result:", result)
+	}
+
+	for i := range labels {
+		if labels[i] != original[i] {
+			t.Fatal("Labels modified")
+		}
+	}
+}
+
+func init() {
+	rand.Seed(1)
+}
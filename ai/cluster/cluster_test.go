@@ -0,0 +1,100 @@
+package cluster
+
+import (
+	v "github.com/deboshire/exp/math/vector"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+var blobCenters = []v.F64{{0, 0}, {10, 0}, {0, 10}}
+
+// Points scattered around blobCenters, n per center.
+func blobs(n int, r *rand.Rand) (points []v.F64) {
+	for i := 0; i < n; i++ {
+		for _, c := range blobCenters {
+			points = append(points, v.F64{c[0] + r.NormFloat64(), c[1] + r.NormFloat64()})
+		}
+	}
+	return
+}
+
+// Checks that every blob center has a cluster center nearby.
+func checkCenters(t *testing.T, name string, centers []v.F64) {
+	for _, c := range blobCenters {
+		if _, d := nearest(centers, c); d > 0.5 {
+			t.Errorf("%s: no center near %v: %v", name, c, centers)
+		}
+	}
+}
+
+func TestKMeans(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	points := blobs(100, r)
+
+	for _, init := range []KMeansInit{KMeansPlusPlus, RandomInit} {
+		m := TrainKMeans(points, KMeansOptions{K: 3, Init: init, Restarts: 5}, r)
+		checkCenters(t, init.String(), m.Centers)
+
+		if inertia := Inertia(points, m.Centers); math.Abs(inertia-m.Inertia) > 1e-9 {
+			t.Errorf("%v: inertia %g, recomputed %g", init, m.Inertia, inertia)
+		}
+		if s := Silhouette(points, m.Assignments, 3); s < 0.7 {
+			t.Errorf("%v: silhouette %g", init, s)
+		}
+		for i, p := range points {
+			if c, _ := m.Nearest(p); c != m.Assignments[i] {
+				t.Errorf("%v: point %d assigned to %d, nearest %d", init, i, m.Assignments[i], c)
+			}
+		}
+	}
+}
+
+func TestKMeansDuplicates(t *testing.T) {
+	points := []v.F64{{1}, {1}, {1}, {2}}
+	m := TrainKMeans(points, KMeansOptions{K: 3}, rand.New(rand.NewSource(1)))
+	if m.Inertia != 0 {
+		t.Errorf("Inertia %g, centers %v", m.Inertia, m.Centers)
+	}
+}
+
+func TestKMeansEmptyClusters(t *testing.T) {
+	points := []v.F64{{0}, {1}, {2}, {10}, {11}}
+	// Both far centers start empty, they must take different points, which
+	// leave the sums of the first cluster.
+	m := lloyd(points, []v.F64{{0}, {100}, {200}}, KMeansOptions{K: 3})
+
+	if !m.Centers[0].Eq(v.F64{1}, 1e-12) || !m.Centers[1].Eq(v.F64{11}, 0) || !m.Centers[2].Eq(v.F64{10}, 0) {
+		t.Error("Bad centers:", m.Centers)
+	}
+	if m.Inertia != 2 {
+		t.Error("Bad inertia:", m.Inertia)
+	}
+}
+
+func TestMiniBatchKMeans(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	points := blobs(1000, r)
+
+	m := TrainMiniBatchKMeans(points, MiniBatchKMeansOptions{KMeansOptions{K: 3, Restarts: 3}, 100}, r)
+	checkCenters(t, "mini-batch", m.Centers)
+
+	full := TrainKMeans(points, KMeansOptions{K: 3}, r)
+	if m.Inertia > full.Inertia*1.05 {
+		t.Errorf("Mini-batch inertia %g, full batch %g", m.Inertia, full.Inertia)
+	}
+}
+
+func TestSilhouette(t *testing.T) {
+	points := []v.F64{{0}, {1}, {4}, {5}, {20}}
+	s := SilhouetteSamples(points, []int{0, 0, 1, 1, 2}, 3)
+
+	// Point 0: a = 1, b = mean(4, 5) = 4.5. Point 1: a = 1, b = 3.5.
+	expected := v.F64{1 - 1/4.5, 1 - 1/3.5, 1 - 1/3.5, 1 - 1/4.5, 0}
+	if !s.Eq(expected, 1e-12) {
+		t.Errorf("Silhouettes %v, expected %v", s, expected)
+	}
+	if m := Silhouette(points, []int{0, 0, 1, 1, 2}, 3); math.Abs(m-expected[:4].DotProduct(v.F64{1, 1, 1, 1})/5) > 1e-12 {
+		t.Errorf("Mean silhouette %g", m)
+	}
+}
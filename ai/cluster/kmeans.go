@@ -0,0 +1,317 @@
+/*
+	Unsupervised clustering of feature vectors.
+*/
+package cluster
+
+import (
+	"fmt"
+	v "github.com/deboshire/exp/math/vector"
+	"math"
+	"math/rand"
+)
+
+type KMeansInit int
+
+const (
+	// Centers chosen one by one with probability proportional to the squared
+	// distance to the nearest center chosen so far (Arthur and Vassilvitskii,
+	// "k-means++: The Advantages of Careful Seeding").
+	KMeansPlusPlus KMeansInit = iota
+	// Centers are distinct random points.
+	RandomInit
+)
+
+func (i KMeansInit) String() string {
+	switch i {
+	case KMeansPlusPlus:
+		return "k-means++"
+	case RandomInit:
+		return "random"
+	}
+	return fmt.Sprintf("KMeansInit(%d)", int(i))
+}
+
+type KMeansOptions struct {
+	// Number of clusters.
+	K    int
+	Init KMeansInit
+	// Number of runs from different initial centers, the one with the
+	// lowest inertia is kept. 1 if <= 0.
+	Restarts int
+	// Maximum number of iterations of every run, 100 if <= 0. For mini-batch
+	// k-means this is the number of batches.
+	MaxIterations int
+	// A run stops when assignments no longer change or no center moves by
+	// more than Tolerance in squared distance.
+	Tolerance float64
+}
+
+const defaultMaxIterations = 100
+
+// Result of k-means clustering.
+type KMeans struct {
+	Centers []v.F64
+	// Cluster of every training point.
+	Assignments []int
+	// Sum of squared distances of training points to their centers.
+	Inertia float64
+	// Iterations made by the kept run.
+	Iterations int
+}
+
+// Lloyd's algorithm: alternately assigns points to the nearest center and
+// moves centers to the means of their points. Initial centers are drawn
+// from r (global source if nil).
+func TrainKMeans(points []v.F64, o KMeansOptions, r *rand.Rand) *KMeans {
+	return restart(points, o, r, func() *KMeans {
+		return lloyd(points, initialCenters(points, o, r), o)
+	})
+}
+
+type MiniBatchKMeansOptions struct {
+	KMeansOptions
+	// Number of points sampled in every iteration.
+	BatchSize int
+}
+
+// Mini-batch k-means (Sculley, "Web-Scale K-Means Clustering"): every
+// iteration moves centers towards a random sample of points, with a per
+// center learning rate decreasing as it gets more points. Only the final
+// assignment and inertia look at all points.
+func TrainMiniBatchKMeans(points []v.F64, o MiniBatchKMeansOptions, r *rand.Rand) *KMeans {
+	if o.BatchSize <= 0 {
+		panic(fmt.Sprintf("Bad batch size: %d", o.BatchSize))
+	}
+	if r == nil {
+		r = rand.New(rand.NewSource(rand.Int63()))
+	}
+
+	return restart(points, o.KMeansOptions, r, func() *KMeans {
+		centers := initialCenters(points, o.KMeansOptions, r)
+		counts := make([]int, len(centers))
+		batch := make([]int, o.BatchSize)
+		assignments := make([]int, o.BatchSize)
+
+		iterations := 0
+		for iterations < maxIterations(o.KMeansOptions) {
+			iterations++
+			for i := range batch {
+				batch[i] = r.Intn(len(points))
+				assignments[i], _ = nearest(centers, points[batch[i]])
+			}
+
+			moved := 0.0
+			for i, p := range batch {
+				c := centers[assignments[i]]
+				old := c.Copy()
+				counts[assignments[i]]++
+				eta := 1 / float64(counts[assignments[i]])
+				c.Mul(1 - eta)
+				step := points[p].Copy()
+				step.Mul(eta)
+				c.Add(step)
+				moved = math.Max(moved, old.Dist2(c))
+			}
+			if moved <= o.Tolerance {
+				break
+			}
+		}
+
+		m := &KMeans{Centers: centers, Iterations: iterations}
+		m.assign(points)
+		return m
+	})
+}
+
+func maxIterations(o KMeansOptions) int {
+	if o.MaxIterations <= 0 {
+		return defaultMaxIterations
+	}
+	return o.MaxIterations
+}
+
+// Runs train o.Restarts times and keeps the result with the lowest inertia.
+func restart(points []v.F64, o KMeansOptions, r *rand.Rand, train func() *KMeans) *KMeans {
+	if o.K <= 0 || o.K > len(points) {
+		panic(fmt.Sprintf("Can not make %d clusters of %d points", o.K, len(points)))
+	}
+
+	var best *KMeans
+	for run := 0; run < o.Restarts || run == 0; run++ {
+		if m := train(); best == nil || m.Inertia < best.Inertia {
+			best = m
+		}
+	}
+	return best
+}
+
+func initialCenters(points []v.F64, o KMeansOptions, r *rand.Rand) (centers []v.F64) {
+	perm := func(n int) []int {
+		if r == nil {
+			return rand.Perm(n)
+		}
+		return r.Perm(n)
+	}
+	float := func() float64 {
+		if r == nil {
+			return rand.Float64()
+		}
+		return r.Float64()
+	}
+
+	switch o.Init {
+	case RandomInit:
+		for _, i := range perm(len(points))[:o.K] {
+			centers = append(centers, points[i].Copy())
+		}
+		return
+
+	case KMeansPlusPlus:
+		centers = append(centers, points[perm(len(points))[0]].Copy())
+		dist2 := v.Zeroes(len(points))
+		for i, p := range points {
+			dist2[i] = p.Dist2(centers[0])
+		}
+
+		for len(centers) < o.K {
+			total := 0.0
+			for _, d := range dist2 {
+				total += d
+			}
+
+			// Points coinciding with centers are never picked, unless all do.
+			next := 0
+			if total == 0 {
+				next = perm(len(points))[0]
+			} else {
+				x := float() * total
+				for next = 0; next < len(points)-1; next++ {
+					if x -= dist2[next]; x < 0 && dist2[next] > 0 {
+						break
+					}
+				}
+			}
+
+			c := points[next].Copy()
+			centers = append(centers, c)
+			for i, p := range points {
+				dist2[i] = math.Min(dist2[i], p.Dist2(c))
+			}
+		}
+		return
+	}
+	panic(fmt.Sprintf("Unknown k-means initialization: %d", o.Init))
+}
+
+func lloyd(points []v.F64, centers []v.F64, o KMeansOptions) *KMeans {
+	m := &KMeans{Centers: centers}
+	m.assign(points)
+	dim := len(points[0])
+
+	for m.Iterations < maxIterations(o) {
+		m.Iterations++
+
+		sums := make([]v.F64, len(centers))
+		counts := make([]int, len(centers))
+		for i := range sums {
+			sums[i] = v.Zeroes(dim)
+		}
+		for i, p := range points {
+			sums[m.Assignments[i]].Add(p)
+			counts[m.Assignments[i]]++
+		}
+
+		// Every empty cluster takes the point farthest from its center,
+		// leaving its old cluster. Points are not taken twice, nor from
+		// clusters they are the only member of.
+		var dist2 v.F64
+		for k := range centers {
+			if counts[k] > 0 {
+				continue
+			}
+			if dist2 == nil {
+				dist2 = m.distances(points)
+			}
+			far := -1
+			for i, d := range dist2 {
+				if counts[m.Assignments[i]] > 1 && (far < 0 || d > dist2[far]) {
+					far = i
+				}
+			}
+			old := m.Assignments[far]
+			sums[old].Sub(points[far])
+			counts[old]--
+			sums[k].Add(points[far])
+			counts[k]++
+			m.Assignments[far] = k
+			dist2[far] = -1
+		}
+
+		moved := 0.0
+		for k, c := range centers {
+			sums[k].Mul(1 / float64(counts[k]))
+			moved = math.Max(moved, c.Dist2(sums[k]))
+			sums[k].CopyTo(c)
+		}
+
+		previous := m.Assignments
+		m.assign(points)
+		changed := false
+		for i := range previous {
+			if previous[i] != m.Assignments[i] {
+				changed = true
+				break
+			}
+		}
+		if !changed || moved <= o.Tolerance {
+			break
+		}
+	}
+	return m
+}
+
+// Squared distances of points to their assigned centers.
+func (m *KMeans) distances(points []v.F64) v.F64 {
+	result := v.Zeroes(len(points))
+	for i, p := range points {
+		result[i] = p.Dist2(m.Centers[m.Assignments[i]])
+	}
+	return result
+}
+
+// Assigns every point to the nearest center and computes inertia.
+func (m *KMeans) assign(points []v.F64) {
+	m.Assignments = make([]int, len(points))
+	m.Inertia = 0
+	for i, p := range points {
+		var d float64
+		m.Assignments[i], d = nearest(m.Centers, p)
+		m.Inertia += d
+	}
+}
+
+// Index of the nearest center and squared distance to it, the first one on
+// ties.
+func nearest(centers []v.F64, point v.F64) (result int, dist2 float64) {
+	dist2 = math.Inf(1)
+	for k, c := range centers {
+		if d := point.Dist2(c); d < dist2 {
+			result, dist2 = k, d
+		}
+	}
+	return
+}
+
+// Nearest center to point and squared distance to it.
+func (m *KMeans) Nearest(point v.F64) (cluster int, dist2 float64) {
+	return nearest(m.Centers, point)
+}
+
+// Sum of squared distances of points to their nearest centers.
+func Inertia(points []v.F64, centers []v.F64) (inertia float64) {
+	for _, p := range points {
+		_, d := nearest(centers, p)
+		inertia += d
+	}
+	return
+}
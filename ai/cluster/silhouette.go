@@ -0,0 +1,62 @@
+package cluster
+
+import (
+	v "github.com/deboshire/exp/math/vector"
+	"math"
+)
+
+// Silhouette of every point: (b-a)/max(a, b), where a is the mean distance
+// to the other points of its cluster and b the smallest mean distance to
+// the points of another cluster. Points alone in their cluster get 0.
+// Takes time quadratic in the number of points.
+func SilhouetteSamples(points []v.F64, assignments []int, k int) v.F64 {
+	counts := make([]int, k)
+	for _, c := range assignments {
+		counts[c]++
+	}
+
+	result := v.Zeroes(len(points))
+	sums := v.Zeroes(k)
+	for i, p := range points {
+		own := assignments[i]
+		if counts[own] == 1 {
+			continue
+		}
+
+		for c := range sums {
+			sums[c] = 0
+		}
+		for j, q := range points {
+			if j != i {
+				sums[assignments[j]] += math.Sqrt(p.Dist2(q))
+			}
+		}
+
+		a := sums[own] / float64(counts[own]-1)
+		b := math.Inf(1)
+		for c, sum := range sums {
+			if c != own && counts[c] > 0 {
+				b = math.Min(b, sum/float64(counts[c]))
+			}
+		}
+		if math.IsInf(b, 1) {
+			// Single cluster.
+			continue
+		}
+		if max := math.Max(a, b); max > 0 {
+			result[i] = (b - a) / max
+		}
+	}
+	return result
+}
+
+// Mean silhouette of all points, between -1 and 1, higher for dense well
+// separated clusters.
+func Silhouette(points []v.F64, assignments []int, k int) float64 {
+	s := SilhouetteSamples(points, assignments, k)
+	total := 0.0
+	for _, x := range s {
+		total += x
+	}
+	return total / float64(len(s))
+}
@@ -0,0 +1,192 @@
+package ai
+
+import (
+	"fmt"
+	v "github.com/deboshire/exp/math/vector"
+	"math"
+	"math/rand"
+)
+
+// Every fold is a list of indexes of examples held out for testing,
+// the rest of examples is used for training.
+type Folds [][]int
+
+type CrossValidationResult struct {
+	// Fraction of correctly classified test examples in every fold.
+	Scores []float64
+	Mean   float64
+	// Standard deviation of Scores.
+	StdDev float64
+}
+
+func perm(r *rand.Rand, n int) []int {
+	if r == nil {
+		return rand.Perm(n)
+	}
+	return r.Perm(n)
+}
+
+func checkFolds(n int, k int) {
+	if k < 2 || k > n {
+		panic(fmt.Sprintf("Bad number of folds: %d for %d examples", k, n))
+	}
+}
+
+// Splits n examples into k folds of nearly equal size at random.
+// Global random source is used if r is nil.
+func KFolds(n int, k int, r *rand.Rand) Folds {
+	checkFolds(n, k)
+
+	p := perm(r, n)
+	folds := make(Folds, k)
+	for i := range folds {
+		folds[i] = p[i*n/k : (i+1)*n/k]
+	}
+	return folds
+}
+
+// Splits examples into k folds so that every fold has roughly the same
+// share of every class.
+func StratifiedKFolds(labels []int, k int, r *rand.Rand) Folds {
+	checkFolds(len(labels), k)
+
+	var classes [][]int
+	for i, l := range labels {
+		for len(classes) <= l {
+			classes = append(classes, nil)
+		}
+		classes[l] = append(classes[l], i)
+	}
+
+	folds := make(Folds, k)
+	fold := 0
+	for _, class := range classes {
+		for _, j := range perm(r, len(class)) {
+			folds[fold] = append(folds[fold], class[j])
+			fold = (fold + 1) % k
+		}
+	}
+	return folds
+}
+
+func StratifiedBinaryKFolds(labels v.B, k int, r *rand.Rand) Folds {
+	return StratifiedKFolds(binaryToNominal(labels), k, r)
+}
+
+// Every example is tested on its own.
+func LeaveOneOutFolds(n int) Folds {
+	folds := make(Folds, n)
+	for i := range folds {
+		folds[i] = []int{i}
+	}
+	return folds
+}
+
+func binaryToNominal(labels v.B) []int {
+	result := make([]int, len(labels))
+	for i, l := range labels {
+		if l {
+			result[i] = 1
+		}
+	}
+	return result
+}
+
+// Training and testing indexes for a fold, in the original order.
+func (folds Folds) split(n int, fold int) (training []int, testing []int) {
+	held := make([]bool, n)
+	for _, i := range folds[fold] {
+		held[i] = true
+	}
+
+	for i := 0; i < n; i++ {
+		if held[i] {
+			testing = append(testing, i)
+		} else {
+			training = append(training, i)
+		}
+	}
+	return
+}
+
+func selectFeatures(features []v.F64, idx []int) []v.F64 {
+	result := make([]v.F64, len(idx))
+	for i, j := range idx {
+		result[i] = features[j]
+	}
+	return result
+}
+
+func newCrossValidationResult(scores []float64) CrossValidationResult {
+	result := CrossValidationResult{Scores: scores}
+	if len(scores) == 0 {
+		return result
+	}
+
+	for _, s := range scores {
+		result.Mean += s
+	}
+	result.Mean /= float64(len(scores))
+
+	for _, s := range scores {
+		result.StdDev += (s - result.Mean) * (s - result.Mean)
+	}
+	result.StdDev = math.Sqrt(result.StdDev / float64(len(scores)))
+	return result
+}
+
+// Trains a classifier on every fold's complement and evaluates it on the
+// fold. Neither features nor labels are modified.
+func CrossValidateBinaryClassifier(
+	features []v.F64,
+	labels v.B,
+	folds Folds,
+	binaryTrainer BinaryClassifierTrainer) CrossValidationResult {
+
+	scores := make([]float64, len(folds))
+	for fold := range folds {
+		training, testing := folds.split(len(features), fold)
+
+		trainingLabels := make(v.B, len(training))
+		for i, j := range training {
+			trainingLabels[i] = labels[j]
+		}
+		testingLabels := make(v.B, len(testing))
+		for i, j := range testing {
+			testingLabels[i] = labels[j]
+		}
+
+		classifier := binaryTrainer(selectFeatures(features, training), trainingLabels)
+		scores[fold] = EvaluateBinaryClassifier(classifier, selectFeatures(features, testing), testingLabels)
+	}
+
+	return newCrossValidationResult(scores)
+}
+
+// Same as CrossValidateBinaryClassifier for nominal classifiers.
+func CrossValidateNominalClassifier(
+	features []v.F64,
+	labels []int,
+	labelsCardinality int,
+	folds Folds,
+	trainer NominalClassifierTrainer) CrossValidationResult {
+
+	scores := make([]float64, len(folds))
+	for fold := range folds {
+		training, testing := folds.split(len(features), fold)
+
+		trainingLabels := make([]int, len(training))
+		for i, j := range training {
+			trainingLabels[i] = labels[j]
+		}
+		testingLabels := make([]int, len(testing))
+		for i, j := range testing {
+			testingLabels[i] = labels[j]
+		}
+
+		classifier := trainer(selectFeatures(features, training), trainingLabels, labelsCardinality)
+		scores[fold] = nominalAccuracy(classifier, selectFeatures(features, testing), testingLabels)
+	}
+
+	return newCrossValidationResult(scores)
+}
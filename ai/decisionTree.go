@@ -0,0 +1,487 @@
+package ai
+
+import (
+	"bytes"
+	"fmt"
+	v "github.com/deboshire/exp/math/vector"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Impurity measure minimized by decision tree splits.
+type SplitCriterion int
+
+const (
+	GiniCriterion SplitCriterion = iota
+	EntropyCriterion
+)
+
+func (c SplitCriterion) String() string {
+	switch c {
+	case GiniCriterion:
+		return "gini"
+	case EntropyCriterion:
+		return "entropy"
+	}
+	return fmt.Sprintf("SplitCriterion(%d)", int(c))
+}
+
+type DecisionTreeOptions struct {
+	Criterion SplitCriterion
+	// Maximum number of splits from the root to a leaf, unlimited if 0.
+	MaxDepth int
+	// Minimum number of training examples in every leaf, at least 1.
+	MinSamplesLeaf int
+	// Cost-complexity pruning parameter: a subtree is replaced by a leaf
+	// unless it lowers the training error rate by more than PruningAlpha per
+	// extra leaf. 0 disables pruning.
+	PruningAlpha float64
+}
+
+// CART decision tree classifier. Nominal; use NewDecisionTreeBinaryTrainer
+// for binary labels.
+type DecisionTree struct {
+	featureDim        int
+	labelsCardinality int
+	// Root is nodes[0].
+	nodes []decisionNode
+}
+
+type decisionNode struct {
+	// Examples with features[feature] <= threshold go to the left child,
+	// others to the right one. Children indexes in nodes, 0 for leaves.
+	feature     int
+	threshold   float64
+	left, right int
+
+	// Weighted fraction of training examples of every class that reached
+	// the node.
+	distribution v.F64
+	// Total weight of training examples that reached the node.
+	weight float64
+}
+
+// Grows CART tree by greedily choosing splits with the largest impurity
+// decrease. Thresholds are midpoints between adjacent training values.
+func TrainDecisionTree(
+	features []v.F64,
+	labels []int,
+	labelsCardinality int,
+	o DecisionTreeOptions) *DecisionTree {
+
+	return trainWeightedDecisionTree(features, labels, labelsCardinality, nil, o, 0, nil)
+}
+
+func NewDecisionTreeTrainer(o DecisionTreeOptions) NominalClassifierTrainer {
+	return func(features []v.F64, labels []int, labelsCardinality int) NominalClassifier {
+		return TrainDecisionTree(features, labels, labelsCardinality, o)
+	}
+}
+
+func NewDecisionTreeBinaryTrainer(o DecisionTreeOptions) BinaryClassifierTrainer {
+	return NewBinaryTrainerFromNominal(NewDecisionTreeTrainer(o))
+}
+
+// Same as TrainDecisionTree with example weights; nil weights are all 1 and
+// examples with weight 0 are left out. If 0 < maxFeatures < number of
+// features, every split considers only maxFeatures features chosen with r.
+func trainWeightedDecisionTree(
+	features []v.F64,
+	labels []int,
+	labelsCardinality int,
+	weights v.F64,
+	o DecisionTreeOptions,
+	maxFeatures int,
+	r *rand.Rand) *DecisionTree {
+
+	if len(features) == 0 {
+		panic("No training data")
+	}
+	if weights == nil {
+		weights = v.Zeroes(len(features))
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+	if o.MinSamplesLeaf < 1 {
+		o.MinSamplesLeaf = 1
+	}
+
+	g := &treeGrower{
+		features:    features,
+		labels:      labels,
+		weights:     weights,
+		o:           o,
+		maxFeatures: maxFeatures,
+		r:           r,
+		tree:        &DecisionTree{featureDim: len(features[0]), labelsCardinality: labelsCardinality},
+	}
+	var examples []int
+	for i, w := range weights {
+		if w > 0 {
+			examples = append(examples, i)
+		}
+	}
+	g.grow(examples, 0)
+
+	if o.PruningAlpha > 0 {
+		g.tree.prune(o.PruningAlpha)
+	}
+	return g.tree
+}
+
+type treeGrower struct {
+	features    []v.F64
+	labels      []int
+	weights     v.F64
+	o           DecisionTreeOptions
+	maxFeatures int
+	r           *rand.Rand
+	tree        *DecisionTree
+}
+
+// Features to consider for the next split, in increasing order.
+func (g *treeGrower) splitFeatures() []int {
+	dim := len(g.features[0])
+	if g.maxFeatures <= 0 || g.maxFeatures >= dim {
+		result := make([]int, dim)
+		for i := range result {
+			result[i] = i
+		}
+		return result
+	}
+
+	result := g.r.Perm(dim)[:g.maxFeatures]
+	sort.Ints(result)
+	return result
+}
+
+func (g *treeGrower) impurity(distribution v.F64) float64 {
+	result := 0.0
+	switch g.o.Criterion {
+	case GiniCriterion:
+		result = 1
+		for _, p := range distribution {
+			result -= p * p
+		}
+	case EntropyCriterion:
+		for _, p := range distribution {
+			if p > 0 {
+				result -= p * math.Log2(p)
+			}
+		}
+	default:
+		panic(fmt.Sprintf("Unknown split criterion: %d", g.o.Criterion))
+	}
+	return result
+}
+
+// Impurity of class weights, scaled by their total.
+func (g *treeGrower) weightedImpurity(classWeights v.F64, total float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	p := classWeights.Copy()
+	p.Mul(1 / total)
+	return total * g.impurity(p)
+}
+
+// Adds the node for examples and returns its index.
+func (g *treeGrower) grow(examples []int, depth int) int {
+	node := decisionNode{distribution: v.Zeroes(g.tree.labelsCardinality)}
+	for _, i := range examples {
+		node.distribution[g.labels[i]] += g.weights[i]
+		node.weight += g.weights[i]
+	}
+	classWeights := node.distribution.Copy()
+	normalize(node.distribution)
+
+	id := len(g.tree.nodes)
+	g.tree.nodes = append(g.tree.nodes, node)
+
+	if g.o.MaxDepth > 0 && depth >= g.o.MaxDepth {
+		return id
+	}
+	if len(examples) < 2*g.o.MinSamplesLeaf || g.impurity(node.distribution) == 0 {
+		return id
+	}
+
+	feature, threshold, ok := g.bestSplit(examples, classWeights, node.weight)
+	if !ok {
+		return id
+	}
+
+	var left, right []int
+	for _, i := range examples {
+		if g.features[i][feature] <= threshold {
+			left = append(left, i)
+		} else {
+			right = append(right, i)
+		}
+	}
+
+	l := g.grow(left, depth+1)
+	r := g.grow(right, depth+1)
+	g.tree.nodes[id].feature = feature
+	g.tree.nodes[id].threshold = threshold
+	g.tree.nodes[id].left = l
+	g.tree.nodes[id].right = r
+	return id
+}
+
+type byFeature struct {
+	examples []int
+	features []v.F64
+	feature  int
+}
+
+func (s byFeature) Len() int      { return len(s.examples) }
+func (s byFeature) Swap(i, j int) { s.examples[i], s.examples[j] = s.examples[j], s.examples[i] }
+func (s byFeature) Less(i, j int) bool {
+	return s.features[s.examples[i]][s.feature] < s.features[s.examples[j]][s.feature]
+}
+
+// Finds the split with the largest impurity decrease. Ties go to the
+// smaller feature and threshold.
+func (g *treeGrower) bestSplit(examples []int, classWeights v.F64, total float64) (feature int, threshold float64, ok bool) {
+	parent := g.weightedImpurity(classWeights, total)
+	best := 0.0
+	sorted := append([]int(nil), examples...)
+	left := v.Zeroes(len(classWeights))
+	right := v.Zeroes(len(classWeights))
+
+	for _, f := range g.splitFeatures() {
+		sort.Stable(byFeature{sorted, g.features, f})
+		for k := range left {
+			left[k] = 0
+		}
+		classWeights.CopyTo(right)
+		leftTotal := 0.0
+
+		for n := 1; n < len(sorted); n++ {
+			i := sorted[n-1]
+			left[g.labels[i]] += g.weights[i]
+			right[g.labels[i]] -= g.weights[i]
+			leftTotal += g.weights[i]
+
+			x, next := g.features[i][f], g.features[sorted[n]][f]
+			if x == next || n < g.o.MinSamplesLeaf || len(sorted)-n < g.o.MinSamplesLeaf {
+				continue
+			}
+
+			decrease := parent - g.weightedImpurity(left, leftTotal) - g.weightedImpurity(right, total-leftTotal)
+			// Ignores decreases that are only rounding errors.
+			if decrease > best+1e-12*total {
+				best, feature, threshold, ok = decrease, f, x+(next-x)/2, true
+			}
+		}
+	}
+	return
+}
+
+// Minimal cost-complexity pruning: repeatedly collapses the internal node
+// with the smallest error decrease per extra leaf while it is <= alpha.
+func (t *DecisionTree) prune(alpha float64) {
+	total := t.nodes[0].weight
+	for {
+		weakest, weakestG := -1, math.Inf(1)
+		// Returns the misclassified weight and the number of leaves of the
+		// subtree, noting its weakest link on the way.
+		var walk func(id int) (float64, int)
+		walk = func(id int) (float64, int) {
+			n := &t.nodes[id]
+			if n.left == 0 {
+				return t.nodeError(id), 1
+			}
+			e1, l1 := walk(n.left)
+			e2, l2 := walk(n.right)
+			g := (t.nodeError(id) - e1 - e2) / total / float64(l1+l2-1)
+			if g < weakestG {
+				weakest, weakestG = id, g
+			}
+			return e1 + e2, l1 + l2
+		}
+		walk(0)
+
+		if weakest < 0 || weakestG > alpha {
+			break
+		}
+		t.nodes[weakest].left = 0
+		t.nodes[weakest].right = 0
+	}
+	t.compact()
+}
+
+// Weight of training examples misclassified if the node were a leaf.
+func (t *DecisionTree) nodeError(id int) float64 {
+	n := &t.nodes[id]
+	return n.weight * (1 - n.distribution[n.distribution.ArgMax()])
+}
+
+// Removes nodes cut off by pruning.
+func (t *DecisionTree) compact() {
+	var nodes []decisionNode
+	var copyNode func(id int) int
+	copyNode = func(id int) int {
+		n := t.nodes[id]
+		result := len(nodes)
+		nodes = append(nodes, n)
+		if n.left != 0 {
+			left := copyNode(n.left)
+			right := copyNode(n.right)
+			nodes[result].left = left
+			nodes[result].right = right
+		} else {
+			nodes[result].feature = 0
+			nodes[result].threshold = 0
+		}
+		return result
+	}
+	copyNode(0)
+	t.nodes = nodes
+}
+
+func (t *DecisionTree) leaf(features v.F64) *decisionNode {
+	n := &t.nodes[0]
+	for n.left != 0 {
+		if features[n.feature] <= n.threshold {
+			n = &t.nodes[n.left]
+		} else {
+			n = &t.nodes[n.right]
+		}
+	}
+	return n
+}
+
+// Class distribution of training examples in the leaf features fall into.
+func (t *DecisionTree) Probabilities(features v.F64) v.F64 {
+	return t.leaf(features).distribution.Copy()
+}
+
+func (t *DecisionTree) LogProbabilities(features v.F64) v.F64 {
+	return logs(t.Probabilities(features))
+}
+
+// Returns the majority class of the leaf and its fraction.
+func (t *DecisionTree) Classify(features v.F64) (result int, confidence float64) {
+	p := t.leaf(features).distribution
+	result = p.ArgMax()
+	return result, p[result]
+}
+
+// Length of the longest path from the root to a leaf.
+func (t *DecisionTree) Depth() int {
+	var depth func(id int) int
+	depth = func(id int) int {
+		n := &t.nodes[id]
+		if n.left == 0 {
+			return 0
+		}
+		l, r := depth(n.left), depth(n.right)
+		if r > l {
+			l = r
+		}
+		return l + 1
+	}
+	return depth(0)
+}
+
+func (t *DecisionTree) NumLeaves() int {
+	leaves := 0
+	for _, n := range t.nodes {
+		if n.left == 0 {
+			leaves++
+		}
+	}
+	return leaves
+}
+
+// Text dump of the tree, one node per line. Feature and class names are
+// used when given, indexes otherwise.
+func (t *DecisionTree) Format(featureNames, classNames []string) string {
+	name := func(names []string, prefix string, i int) string {
+		if i < len(names) {
+			return names[i]
+		}
+		return fmt.Sprintf("%s%d", prefix, i)
+	}
+
+	var b bytes.Buffer
+	var format func(id int, indent string)
+	format = func(id int, indent string) {
+		n := &t.nodes[id]
+		if n.left == 0 {
+			class := n.distribution.ArgMax()
+			fmt.Fprintf(&b, "%s%s (%.3f of %g)\n", indent, name(classNames, "class ", class), n.distribution[class], n.weight)
+			return
+		}
+		feature := name(featureNames, "x", n.feature)
+		fmt.Fprintf(&b, "%s%s <= %g\n", indent, feature, n.threshold)
+		format(n.left, indent+"  ")
+		fmt.Fprintf(&b, "%s%s > %g\n", indent, feature, n.threshold)
+		format(n.right, indent+"  ")
+	}
+	format(0, "")
+	return b.String()
+}
+
+func (t *DecisionTree) String() string {
+	return t.Format(nil, nil)
+}
+
+const decisionTreeModelType = "ai.decisionTree"
+
+type decisionTreeState struct {
+	FeatureDim        int
+	LabelsCardinality int
+	Nodes             []decisionNodeState
+}
+
+type decisionNodeState struct {
+	Feature      int
+	Threshold    float64
+	Left, Right  int
+	Distribution v.F64
+	Weight       float64
+}
+
+func (t *DecisionTree) ModelType() string { return decisionTreeModelType }
+func (t *DecisionTree) FeatureDim() int   { return t.featureDim }
+
+func (t *DecisionTree) MarshalModel(e Encoding) ([]byte, error) {
+	s := decisionTreeState{FeatureDim: t.featureDim, LabelsCardinality: t.labelsCardinality}
+	for _, n := range t.nodes {
+		s.Nodes = append(s.Nodes, decisionNodeState{n.feature, n.threshold, n.left, n.right, n.distribution, n.weight})
+	}
+	return e.Marshal(s)
+}
+
+func loadDecisionTree(e Encoding, data []byte) (Model, error) {
+	var s decisionTreeState
+	if err := e.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if len(s.Nodes) == 0 {
+		return nil, errEmptyModel
+	}
+
+	t := &DecisionTree{featureDim: s.FeatureDim, labelsCardinality: s.LabelsCardinality}
+	for id, n := range s.Nodes {
+		if len(n.Distribution) != s.LabelsCardinality {
+			return nil, fmt.Errorf("Node %d has %d classes instead of %d", id, len(n.Distribution), s.LabelsCardinality)
+		}
+		if n.Left != 0 && (n.Feature < 0 || n.Feature >= s.FeatureDim) {
+			return nil, fmt.Errorf("Node %d splits on feature %d out of %d", id, n.Feature, s.FeatureDim)
+		}
+		if n.Left != 0 && (n.Left <= id || n.Right <= id || n.Left >= len(s.Nodes) || n.Right >= len(s.Nodes)) {
+			return nil, fmt.Errorf("Node %d has bad children %d and %d", id, n.Left, n.Right)
+		}
+		t.nodes = append(t.nodes, decisionNode{n.Feature, n.Threshold, n.Left, n.Right, n.Distribution, n.Weight})
+	}
+	return t, nil
+}
+
+func init() {
+	RegisterModel(decisionTreeModelType, loadDecisionTree)
+}
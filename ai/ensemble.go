@@ -0,0 +1,304 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	v "github.com/deboshire/exp/math/vector"
+	"math"
+	"math/rand"
+)
+
+// Classifiers trained on bootstrap samples of the training set. Averages
+// their probabilities, or votes for classifiers that are not probabilistic.
+type Ensemble struct {
+	labelsCardinality int
+	classifiers       []NominalClassifier
+	oobAccuracy       float64
+}
+
+type EnsembleOptions struct {
+	// Number of classifiers.
+	Size int
+	// Number of classifiers trained at once, GOMAXPROCS if <= 0.
+	Concurrency int
+}
+
+type RandomForestOptions struct {
+	EnsembleOptions
+	Tree DecisionTreeOptions
+	// Number of features considered at every split, square root of the
+	// number of features if 0.
+	MaxFeatures int
+}
+
+// Trains o.Size classifiers, each on its own bootstrap sample: as many
+// examples as in the training set drawn with replacement.
+// Every trainer gets a sample drawn from its own random source seeded from
+// r (global source if nil), so a seeded r gives the same ensemble regardless
+// of concurrency. A panic in any trainer is returned as *TrainingError with
+// the classifier index.
+func TrainBagging(
+	features []v.F64,
+	labels []int,
+	labelsCardinality int,
+	trainer NominalClassifierTrainer,
+	o EnsembleOptions,
+	r *rand.Rand) (*Ensemble, error) {
+
+	return trainEnsemble(features, labels, labelsCardinality, o, r, func(counts []int, r *rand.Rand) NominalClassifier {
+		var sampleFeatures []v.F64
+		var sampleLabels []int
+		for i, n := range counts {
+			for ; n > 0; n-- {
+				sampleFeatures = append(sampleFeatures, features[i])
+				sampleLabels = append(sampleLabels, labels[i])
+			}
+		}
+		return trainer(sampleFeatures, sampleLabels, labelsCardinality)
+	})
+}
+
+// Bagging of decision trees, each split choosing from a random subset of
+// features.
+func TrainRandomForest(
+	features []v.F64,
+	labels []int,
+	labelsCardinality int,
+	o RandomForestOptions,
+	r *rand.Rand) (*Ensemble, error) {
+
+	maxFeatures := o.MaxFeatures
+	if maxFeatures <= 0 {
+		maxFeatures = int(math.Max(1, math.Floor(math.Sqrt(float64(len(features[0]))))))
+	}
+
+	return trainEnsemble(features, labels, labelsCardinality, o.EnsembleOptions, r, func(counts []int, r *rand.Rand) NominalClassifier {
+		// Bootstrap multiplicities are used as example weights.
+		weights := v.Zeroes(len(counts))
+		for i, n := range counts {
+			weights[i] = float64(n)
+		}
+		return trainWeightedDecisionTree(features, labels, labelsCardinality, weights, o.Tree, maxFeatures, r)
+	})
+}
+
+// Panics on training errors. Not safe for concurrent use unless r is.
+func NewRandomForestTrainer(o RandomForestOptions, r *rand.Rand) NominalClassifierTrainer {
+	return func(features []v.F64, labels []int, labelsCardinality int) NominalClassifier {
+		c, err := TrainRandomForest(features, labels, labelsCardinality, o, r)
+		if err != nil {
+			panic(err)
+		}
+		return c
+	}
+}
+
+func NewRandomForestRandTrainer(o RandomForestOptions) RandBinaryClassifierTrainer {
+	return func(features []v.F64, labels []bool, r *rand.Rand) BinaryClassifier {
+		return NewBinaryTrainerFromNominal(NewRandomForestTrainer(o, r))(features, labels)
+	}
+}
+
+// Trains classifiers on bootstrap samples given by the number of times every
+// example was drawn.
+func trainEnsemble(
+	features []v.F64,
+	labels []int,
+	labelsCardinality int,
+	o EnsembleOptions,
+	r *rand.Rand,
+	train func(counts []int, r *rand.Rand) NominalClassifier) (*Ensemble, error) {
+
+	if len(features) == 0 {
+		panic("No training data")
+	}
+	if o.Size <= 0 {
+		panic(fmt.Sprintf("Bad ensemble size: %d", o.Size))
+	}
+
+	c := &Ensemble{labelsCardinality: labelsCardinality, classifiers: make([]NominalClassifier, o.Size)}
+	bags := make([][]int, o.Size)
+
+	err := runConcurrently(o.Size, o.Concurrency, r, func(i int, r *rand.Rand) {
+		counts := make([]int, len(features))
+		for range features {
+			counts[r.Intn(len(features))]++
+		}
+		bags[i] = counts
+		c.classifiers[i] = train(counts, r)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.oobAccuracy = c.outOfBagAccuracy(features, labels, bags)
+	return c, nil
+}
+
+// Accuracy on training examples of the classifiers that did not see them.
+// Examples that were in every sample are skipped.
+func (c *Ensemble) outOfBagAccuracy(features []v.F64, labels []int, bags [][]int) float64 {
+	evaluated, successes := 0, 0
+	for i, f := range features {
+		p := v.Zeroes(c.labelsCardinality)
+		voters := 0
+		for k, classifier := range c.classifiers {
+			if bags[k][i] == 0 {
+				p.Add(memberProbabilities(classifier, f, c.labelsCardinality))
+				voters++
+			}
+		}
+		if voters == 0 {
+			continue
+		}
+
+		evaluated++
+		if p.ArgMax() == labels[i] {
+			successes++
+		}
+	}
+
+	if evaluated == 0 {
+		return math.NaN()
+	}
+	return float64(successes) / float64(evaluated)
+}
+
+func memberProbabilities(c NominalClassifier, features v.F64, labelsCardinality int) v.F64 {
+	if p, ok := c.(ProbabilisticClassifier); ok {
+		return p.Probabilities(features)
+	}
+	p := v.Zeroes(labelsCardinality)
+	result, _ := c.Classify(features)
+	p[result] = 1
+	return p
+}
+
+// Out-of-bag estimate of the accuracy on unseen data, NaN if every example
+// was used to train every classifier.
+func (c *Ensemble) OOBAccuracy() float64 {
+	return c.oobAccuracy
+}
+
+func (c *Ensemble) Size() int {
+	return len(c.classifiers)
+}
+
+func (c *Ensemble) Probabilities(features v.F64) v.F64 {
+	p := v.Zeroes(c.labelsCardinality)
+	for _, classifier := range c.classifiers {
+		p.Add(memberProbabilities(classifier, features, c.labelsCardinality))
+	}
+	p.Mul(1 / float64(len(c.classifiers)))
+	return p
+}
+
+func (c *Ensemble) LogProbabilities(features v.F64) v.F64 {
+	return logs(c.Probabilities(features))
+}
+
+// Confidence is the averaged probability of the returned class.
+func (c *Ensemble) Classify(features v.F64) (result int, confidence float64) {
+	p := c.Probabilities(features)
+	result = p.ArgMax()
+	return result, p[result]
+}
+
+// Decrease of accuracy of c on the given examples when values of a feature
+// are shuffled among them, for every feature, averaged over repeats
+// shuffles. Features are not modified.
+func PermutationImportance(c NominalClassifier, features []v.F64, labels []int, repeats int, r *rand.Rand) v.F64 {
+	if len(features) == 0 {
+		panic("No examples")
+	}
+	if repeats <= 0 {
+		panic(fmt.Sprintf("Bad number of repeats: %d", repeats))
+	}
+	if r == nil {
+		r = rand.New(rand.NewSource(rand.Int63()))
+	}
+
+	baseline := nominalAccuracy(c, features, labels)
+	importance := v.Zeroes(len(features[0]))
+	row := v.Zeroes(len(features[0]))
+
+	for j := range importance {
+		for n := 0; n < repeats; n++ {
+			successes := 0
+			for i, source := range r.Perm(len(features)) {
+				features[i].CopyTo(row)
+				row[j] = features[source][j]
+				if l, _ := c.Classify(row); l == labels[i] {
+					successes++
+				}
+			}
+			importance[j] += baseline - float64(successes)/float64(len(features))
+		}
+	}
+	importance.Mul(1 / float64(repeats))
+	return importance
+}
+
+const ensembleModelType = "ai.ensemble"
+
+type ensembleState struct {
+	LabelsCardinality int
+	// Missing if NaN, which JSON can not represent.
+	OOBAccuracy *float64
+	Classifiers []json.RawMessage
+}
+
+func (c *Ensemble) ModelType() string { return ensembleModelType }
+func (c *Ensemble) FeatureDim() int   { return featureDim(c.classifiers[0]) }
+
+func (c *Ensemble) MarshalModel(e Encoding) ([]byte, error) {
+	s := ensembleState{LabelsCardinality: c.labelsCardinality}
+	if !math.IsNaN(c.oobAccuracy) {
+		s.OOBAccuracy = &c.oobAccuracy
+	}
+	for _, classifier := range c.classifiers {
+		m, err := asModel(classifier)
+		if err != nil {
+			return nil, err
+		}
+		data, err := EncodeModel(m, e)
+		if err != nil {
+			return nil, err
+		}
+		s.Classifiers = append(s.Classifiers, data)
+	}
+	return e.Marshal(s)
+}
+
+func loadEnsemble(e Encoding, data []byte) (Model, error) {
+	var s ensembleState
+	if err := e.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if len(s.Classifiers) == 0 {
+		return nil, errEmptyModel
+	}
+
+	c := &Ensemble{labelsCardinality: s.LabelsCardinality, oobAccuracy: math.NaN()}
+	if s.OOBAccuracy != nil {
+		c.oobAccuracy = *s.OOBAccuracy
+	}
+	dim := -1
+	for _, data := range s.Classifiers {
+		m, err := decodeMember(data, e, &dim)
+		if err != nil {
+			return nil, err
+		}
+
+		classifier, ok := m.(NominalClassifier)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a nominal classifier", m.ModelType())
+		}
+		c.classifiers = append(c.classifiers, classifier)
+	}
+	return c, nil
+}
+
+func init() {
+	RegisterModel(ensembleModelType, loadEnsemble)
+}
@@ -0,0 +1,352 @@
+/*
+	Gaussian mixture models fitted with Expectation-Maximization.
+*/
+package gmm
+
+import (
+	"fmt"
+	"github.com/deboshire/exp/ai/cluster"
+	"github.com/deboshire/exp/math/matrix"
+	v "github.com/deboshire/exp/math/vector"
+	"github.com/deboshire/exp/tracer"
+	"math"
+	"math/rand"
+)
+
+type CovarianceType int
+
+const (
+	// Every component has its own unrestricted covariance matrix.
+	Full CovarianceType = iota
+	// Covariance matrices are diagonal: features are independent within a
+	// component.
+	Diagonal
+	// Covariance matrices are multiples of identity.
+	Spherical
+)
+
+func (c CovarianceType) String() string {
+	switch c {
+	case Full:
+		return "full"
+	case Diagonal:
+		return "diagonal"
+	case Spherical:
+		return "spherical"
+	}
+	return fmt.Sprintf("CovarianceType(%d)", int(c))
+}
+
+type Options struct {
+	// Number of components.
+	K          int
+	Covariance CovarianceType
+	// Maximum number of EM iterations, 100 if <= 0.
+	MaxIterations int
+	// EM stops when the log-likelihood per point improves by less than
+	// Tolerance, 1e-3 if 0.
+	Tolerance float64
+	// Added to variances to keep covariance matrices positive definite,
+	// 1e-6 if 0.
+	Regularization float64
+}
+
+const (
+	defaultMaxIterations  = 100
+	defaultTolerance      = 1e-3
+	defaultRegularization = 1e-6
+)
+
+// Fitted mixture. Fields should not be modified, values derived from
+// covariances are cached.
+type Mixture struct {
+	Covariance CovarianceType
+	// Mixing probabilities of components.
+	Weights v.F64
+	Means   []v.F64
+	// Covariance of every component: dim x dim matrix in row-major order
+	// for Full, vector of variances for Diagonal and a single variance for
+	// Spherical.
+	Covariances []v.F64
+	// Log-likelihood of the training points after every EM iteration.
+	LogLikelihoods v.F64
+
+	// Decompositions of full covariances.
+	cholesky []*matrix.Cholesky
+	// Variances expanded to all dimensions for other covariance types.
+	variances []v.F64
+	logDets   v.F64
+}
+
+// Fits mixture of o.K Gaussians to points by EM, starting from k-means++
+// clusters drawn from r (global source if nil). Traces log-likelihood of
+// every iteration to t (nothing if nil).
+func Fit(points []v.F64, o Options, t tracer.Tracer, r *rand.Rand) *Mixture {
+	if t == nil {
+		t = tracer.DefaultTracer()
+	}
+	if o.Covariance != Full && o.Covariance != Diagonal && o.Covariance != Spherical {
+		panic(fmt.Sprintf("Unknown covariance type: %d", o.Covariance))
+	}
+	if o.Tolerance == 0 {
+		o.Tolerance = defaultTolerance
+	}
+	if o.Regularization == 0 {
+		o.Regularization = defaultRegularization
+	}
+	maxIterations := o.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = defaultMaxIterations
+	}
+
+	km := cluster.TrainKMeans(points, cluster.KMeansOptions{K: o.K}, r)
+	resp := make([]v.F64, len(points))
+	for i, c := range km.Assignments {
+		resp[i] = v.Zeroes(o.K)
+		resp[i][c] = 1
+	}
+
+	m := &Mixture{Covariance: o.Covariance}
+	previous := math.Inf(-1)
+	for iteration := 0; iteration < maxIterations; iteration++ {
+		m.maximize(points, resp, o.Regularization)
+		logLikelihood := m.expect(points, resp)
+		m.LogLikelihoods = append(m.LogLikelihoods, logLikelihood)
+		t.TraceInt("iteration", iteration)
+		t.TraceFloat64("logLikelihood", logLikelihood)
+
+		if (logLikelihood-previous)/float64(len(points)) < o.Tolerance {
+			break
+		}
+		previous = logLikelihood
+	}
+	return m
+}
+
+// M-step: parameters maximizing expected log-likelihood given
+// responsibilities of components for points.
+func (m *Mixture) maximize(points []v.F64, resp []v.F64, regularization float64) {
+	k, dim := len(resp[0]), len(points[0])
+	m.Weights = v.Zeroes(k)
+	m.Means = make([]v.F64, k)
+	m.Covariances = make([]v.F64, k)
+
+	for c := 0; c < k; c++ {
+		// Keeps empty components finite.
+		total := 1e-10
+		mean := v.Zeroes(dim)
+		for i, p := range points {
+			total += resp[i][c]
+			for j, x := range p {
+				mean[j] += resp[i][c] * x
+			}
+		}
+		mean.Mul(1 / total)
+
+		var cov v.F64
+		if m.Covariance == Full {
+			cov = v.Zeroes(dim * dim)
+		} else {
+			cov = v.Zeroes(dim)
+		}
+		d := v.Zeroes(dim)
+		for i, p := range points {
+			p.CopyTo(d)
+			d.Sub(mean)
+			for a := range d {
+				if m.Covariance != Full {
+					cov[a] += resp[i][c] * d[a] * d[a]
+					continue
+				}
+				for b := 0; b <= a; b++ {
+					cov[a*dim+b] += resp[i][c] * d[a] * d[b]
+				}
+			}
+		}
+		cov.Mul(1 / total)
+
+		switch m.Covariance {
+		case Full:
+			for a := 0; a < dim; a++ {
+				cov[a*dim+a] += regularization
+				for b := 0; b < a; b++ {
+					cov[b*dim+a] = cov[a*dim+b]
+				}
+			}
+		case Diagonal:
+			for a := range cov {
+				cov[a] += regularization
+			}
+		case Spherical:
+			variance := 0.0
+			for _, x := range cov {
+				variance += x
+			}
+			cov = v.F64{variance/float64(dim) + regularization}
+		}
+
+		m.Weights[c] = total / float64(len(points))
+		m.Means[c] = mean
+		m.Covariances[c] = cov
+	}
+	m.Weights.Mul(1 / m.Weights.Sum())
+	m.factorize()
+}
+
+// E-step: replaces resp with posterior probabilities of components and
+// returns log-likelihood of points.
+func (m *Mixture) expect(points []v.F64, resp []v.F64) (logLikelihood float64) {
+	for i, p := range points {
+		m.logJoint(p, resp[i])
+		lse := resp[i].LogSumExp()
+		for c := range resp[i] {
+			resp[i][c] = math.Exp(resp[i][c] - lse)
+		}
+		logLikelihood += lse
+	}
+	return
+}
+
+// Computes factors and log-determinants of covariances.
+func (m *Mixture) factorize() {
+	dim := len(m.Means[0])
+	m.cholesky = make([]*matrix.Cholesky, len(m.Covariances))
+	m.variances = make([]v.F64, len(m.Covariances))
+	m.logDets = v.Zeroes(len(m.Covariances))
+
+	for c, cov := range m.Covariances {
+		switch m.Covariance {
+		case Full:
+			ch, ok := matrix.NewCholesky(matrix.NewFromData(dim, dim, cov))
+			if !ok {
+				panic(fmt.Sprintf("Covariance is not positive definite: %v", cov))
+			}
+			m.cholesky[c] = ch
+			m.logDets[c] = ch.LogDet()
+		case Diagonal, Spherical:
+			variances := v.Zeroes(dim)
+			for a := range variances {
+				variances[a] = cov[a%len(cov)]
+				m.logDets[c] += math.Log(variances[a])
+			}
+			m.variances[c] = variances
+		default:
+			panic(fmt.Sprintf("Unknown covariance type: %d", m.Covariance))
+		}
+	}
+}
+
+// Replaces out with log(weight * density) of every component at point.
+func (m *Mixture) logJoint(point v.F64, out v.F64) {
+	if m.logDets == nil {
+		m.factorize()
+	}
+	dim := len(point)
+	d := v.Zeroes(dim)
+	for c, mean := range m.Means {
+		point.CopyTo(d)
+		d.Sub(mean)
+
+		// Squared Mahalanobis distance.
+		var mahalanobis float64
+		if m.Covariance == Full {
+			y := m.cholesky[c].SolveLVec(d)
+			mahalanobis = y.DotProduct(y)
+		} else {
+			for a, x := range d {
+				mahalanobis += x * x / m.variances[c][a]
+			}
+		}
+
+		out[c] = math.Log(m.Weights[c]) - 0.5*(float64(dim)*math.Log(2*math.Pi)+m.logDets[c]+mahalanobis)
+	}
+}
+
+// Log of the mixture density at point.
+func (m *Mixture) LogDensity(point v.F64) float64 {
+	joint := v.Zeroes(len(m.Weights))
+	m.logJoint(point, joint)
+	return joint.LogSumExp()
+}
+
+// Sum of log densities of points.
+func (m *Mixture) LogLikelihood(points []v.F64) (logLikelihood float64) {
+	for _, p := range points {
+		logLikelihood += m.LogDensity(p)
+	}
+	return
+}
+
+// Posterior probabilities of components having generated point.
+func (m *Mixture) Responsibilities(point v.F64) v.F64 {
+	joint := v.Zeroes(len(m.Weights))
+	m.logJoint(point, joint)
+	lse := joint.LogSumExp()
+	for c := range joint {
+		joint[c] = math.Exp(joint[c] - lse)
+	}
+	return joint
+}
+
+// Most probable component to have generated point.
+func (m *Mixture) Component(point v.F64) int {
+	return m.Responsibilities(point).ArgMax()
+}
+
+// Number of free parameters of the model.
+func (m *Mixture) NumParameters() int {
+	k, dim := len(m.Weights), len(m.Means[0])
+	covariance := 0
+	switch m.Covariance {
+	case Full:
+		covariance = dim * (dim + 1) / 2
+	case Diagonal:
+		covariance = dim
+	case Spherical:
+		covariance = 1
+	}
+	return k - 1 + k*dim + k*covariance
+}
+
+// Bayesian information criterion on points, lower is better.
+func (m *Mixture) BIC(points []v.F64) float64 {
+	return -2*m.LogLikelihood(points) + float64(m.NumParameters())*math.Log(float64(len(points)))
+}
+
+// Akaike information criterion on points, lower is better.
+func (m *Mixture) AIC(points []v.F64) float64 {
+	return -2*m.LogLikelihood(points) + 2*float64(m.NumParameters())
+}
+
+// Draws a point from the mixture using r (global source if nil).
+func (m *Mixture) Sample(r *rand.Rand) v.F64 {
+	if m.logDets == nil {
+		m.factorize()
+	}
+	if r == nil {
+		r = rand.New(rand.NewSource(rand.Int63()))
+	}
+
+	c, x := 0, r.Float64()
+	for ; c < len(m.Weights)-1; c++ {
+		if x -= m.Weights[c]; x < 0 {
+			break
+		}
+	}
+
+	dim := len(m.Means[c])
+	z := v.Zeroes(dim)
+	for a := range z {
+		z[a] = r.NormFloat64()
+	}
+	result := m.Means[c].Copy()
+	if m.Covariance == Full {
+		result.Add(m.cholesky[c].L().MulVec(z))
+		return result
+	}
+	for a := range result {
+		result[a] += math.Sqrt(m.variances[c][a]) * z[a]
+	}
+	return result
+}
+
@@ -0,0 +1,113 @@
+package gmm
+
+import (
+	v "github.com/deboshire/exp/math/vector"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Correlated Gaussian around (0, 0) and a round one around (8, 8), twice as
+// many points in the first.
+func mixture(n int, r *rand.Rand) (points []v.F64) {
+	for i := 0; i < n; i++ {
+		z := r.NormFloat64()
+		points = append(points, v.F64{z, 0.8*z + 0.6*r.NormFloat64()})
+		if i%2 == 0 {
+			points = append(points, v.F64{8 + r.NormFloat64(), 8 + r.NormFloat64()})
+		}
+	}
+	return
+}
+
+type recordingTracer struct {
+	logLikelihoods v.F64
+}
+
+func (t *recordingTracer) TraceFloat64(label string, value float64) {
+	if label == "logLikelihood" {
+		t.logLikelihoods = append(t.logLikelihoods, value)
+	}
+}
+func (t *recordingTracer) TraceF64(label string, value v.F64) {}
+func (t *recordingTracer) TraceInt(label string, value int)   {}
+
+func TestFit(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	points := mixture(1000, r)
+
+	for _, cov := range []CovarianceType{Full, Diagonal, Spherical} {
+		tr := &recordingTracer{}
+		m := Fit(points, Options{K: 2, Covariance: cov}, tr, r)
+
+		if !tr.logLikelihoods.Eq(m.LogLikelihoods, 0) {
+			t.Errorf("%v: traced %v, recorded %v", cov, tr.logLikelihoods, m.LogLikelihoods)
+		}
+		for i := 1; i < len(m.LogLikelihoods); i++ {
+			if m.LogLikelihoods[i] < m.LogLikelihoods[i-1]-1e-6 {
+				t.Errorf("%v: log-likelihood decreased: %v", cov, m.LogLikelihoods)
+			}
+		}
+		if ll := m.LogLikelihood(points); math.Abs(ll-m.LogLikelihoods[len(m.LogLikelihoods)-1]) > 1e-6 {
+			t.Errorf("%v: log-likelihood %g, last iteration %g", cov, ll, m.LogLikelihoods[len(m.LogLikelihoods)-1])
+		}
+
+		c := m.Component(v.F64{0, 0})
+		if m.Component(v.F64{8, 8}) == c {
+			t.Errorf("%v: both clusters in component %d", cov, c)
+		}
+		if !m.Means[c].Eq(v.F64{0, 0}, 0.1) || !m.Means[1-c].Eq(v.F64{8, 8}, 0.1) {
+			t.Errorf("%v: means %v", cov, m.Means)
+		}
+		if math.Abs(m.Weights[c]-2.0/3) > 0.01 {
+			t.Errorf("%v: weights %v", cov, m.Weights)
+		}
+	}
+
+	m := Fit(points, Options{K: 2, Covariance: Full}, nil, r)
+	if !m.Covariances[m.Component(v.F64{0, 0})].Eq(v.F64{1, 0.8, 0.8, 1}, 0.1) {
+		t.Errorf("Covariances %v", m.Covariances)
+	}
+}
+
+func TestModelSelection(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	points := mixture(300, r)
+
+	bestK, bestBIC := 0, math.Inf(1)
+	for k := 1; k <= 4; k++ {
+		m := Fit(points, Options{K: k, Covariance: Full}, nil, r)
+		if bic := m.BIC(points); bic < bestBIC {
+			bestK, bestBIC = k, bic
+		}
+		if aic, bic := m.AIC(points), m.BIC(points); aic >= bic {
+			t.Errorf("AIC %g not below BIC %g for %d points", aic, bic, len(points))
+		}
+	}
+	if bestK != 2 {
+		t.Errorf("BIC chose %d components", bestK)
+	}
+
+	spherical := Fit(points, Options{K: 2, Covariance: Spherical}, nil, r)
+	if n := spherical.NumParameters(); n != 1+2*2+2 {
+		t.Errorf("%d parameters of spherical mixture", n)
+	}
+}
+
+func TestSample(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	m := Fit(mixture(1000, r), Options{K: 2, Covariance: Full}, nil, r)
+
+	var samples []v.F64
+	for i := 0; i < 3000; i++ {
+		samples = append(samples, m.Sample(r))
+	}
+
+	refit := Fit(samples, Options{K: 2, Covariance: Full}, nil, r)
+	for c, mean := range m.Means {
+		other := refit.Component(mean)
+		if !refit.Means[other].Eq(mean, 0.15) || !refit.Covariances[other].Eq(m.Covariances[c], 0.15) {
+			t.Errorf("Component %v %v refitted as %v %v", mean, m.Covariances[c], refit.Means[other], refit.Covariances[other])
+		}
+	}
+}
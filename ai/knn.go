@@ -0,0 +1,163 @@
+package ai
+
+import (
+	"fmt"
+	v "github.com/deboshire/exp/math/vector"
+	"math"
+)
+
+// k-nearest-neighbour classifier. Training only builds the spatial index;
+// all the work is done by Classify.
+type knnClassifier struct {
+	features          []v.F64
+	labels            []int
+	labelsCardinality int
+	k                 int
+	weighted          bool
+	index             NeighbourIndex
+	finder            neighbourFinder
+}
+
+// Trains k-nearest-neighbour classifier. Every neighbour votes for its
+// class, weighted by the inverse of its distance if weighted is true. Points
+// coinciding with the query outvote all others in the weighted mode.
+// Features are not copied and must not be modified afterwards.
+func TrainKNNClassifier(
+	features []v.F64,
+	labels []int,
+	labelsCardinality int,
+	k int,
+	weighted bool,
+	index NeighbourIndex) NominalClassifier {
+
+	if len(features) == 0 {
+		panic("No training data")
+	}
+	if k <= 0 {
+		panic(fmt.Sprintf("Bad number of neighbours: %d", k))
+	}
+
+	c := &knnClassifier{
+		features:          features,
+		labels:            labels,
+		labelsCardinality: labelsCardinality,
+		k:                 k,
+		weighted:          weighted,
+		index:             index,
+	}
+	c.finder = newNeighbourFinder(features, index)
+	return c
+}
+
+func NewKNNTrainer(k int, weighted bool, index NeighbourIndex) NominalClassifierTrainer {
+	return func(features []v.F64, labels []int, labelsCardinality int) NominalClassifier {
+		return TrainKNNClassifier(features, labels, labelsCardinality, k, weighted, index)
+	}
+}
+
+func NewKNNBinaryTrainer(k int, weighted bool, index NeighbourIndex) BinaryClassifierTrainer {
+	return NewBinaryTrainerFromNominal(NewKNNTrainer(k, weighted, index))
+}
+
+// Vote share of every class among the k nearest neighbours.
+func (c *knnClassifier) Probabilities(features v.F64) v.F64 {
+	p := v.Zeroes(c.labelsCardinality)
+	neighbours := c.finder.nearest(features, c.k)
+
+	if !c.weighted {
+		for _, n := range neighbours {
+			p[c.labels[n.index]]++
+		}
+		normalize(p)
+		return p
+	}
+
+	if neighbours[0].dist2 == 0 {
+		for _, n := range neighbours {
+			if n.dist2 == 0 {
+				p[c.labels[n.index]]++
+			}
+		}
+	} else {
+		for _, n := range neighbours {
+			p[c.labels[n.index]] += 1 / math.Sqrt(n.dist2)
+		}
+	}
+	normalize(p)
+	return p
+}
+
+func (c *knnClassifier) LogProbabilities(features v.F64) v.F64 {
+	return logs(c.Probabilities(features))
+}
+
+// Confidence is the vote share of the returned class. Ties go to the
+// smaller label.
+func (c *knnClassifier) Classify(features v.F64) (result int, confidence float64) {
+	p := c.Probabilities(features)
+	for k := range p {
+		if p[k] > p[result] {
+			result = k
+		}
+	}
+	return result, p[result]
+}
+
+const knnModelType = "ai.knn"
+
+// The index is not saved but rebuilt on load.
+type knnState struct {
+	Features          []v.F64
+	Labels            []int
+	LabelsCardinality int
+	K                 int
+	Weighted          bool
+	Index             NeighbourIndex
+}
+
+func (c *knnClassifier) ModelType() string { return knnModelType }
+func (c *knnClassifier) FeatureDim() int   { return len(c.features[0]) }
+
+func (c *knnClassifier) MarshalModel(e Encoding) ([]byte, error) {
+	return e.Marshal(knnState{
+		Features:          c.features,
+		Labels:            c.labels,
+		LabelsCardinality: c.labelsCardinality,
+		K:                 c.k,
+		Weighted:          c.weighted,
+		Index:             c.index,
+	})
+}
+
+func loadKNN(e Encoding, data []byte) (Model, error) {
+	var s knnState
+	if err := e.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if len(s.Features) == 0 || len(s.Features[0]) == 0 {
+		return nil, errEmptyModel
+	}
+	if len(s.Labels) != len(s.Features) {
+		return nil, fmt.Errorf("%d labels for %d points", len(s.Labels), len(s.Features))
+	}
+	if s.K <= 0 {
+		return nil, fmt.Errorf("Bad number of neighbours: %d", s.K)
+	}
+	if s.Index < AutoIndex || s.Index > BallTreeIndex {
+		return nil, fmt.Errorf("Unknown neighbour index: %d", s.Index)
+	}
+	for i, f := range s.Features {
+		if len(f) != len(s.Features[0]) {
+			return nil, fmt.Errorf("Point %d has %d features instead of %d", i, len(f), len(s.Features[0]))
+		}
+		if s.Labels[i] < 0 || s.Labels[i] >= s.LabelsCardinality {
+			return nil, fmt.Errorf("Point %d has label %d out of [0, %d)", i, s.Labels[i], s.LabelsCardinality)
+		}
+	}
+
+	return TrainKNNClassifier(s.Features, s.Labels, s.LabelsCardinality, s.K, s.Weighted, s.Index).(Model), nil
+}
+
+func init() {
+	RegisterModel(knnModelType, loadKNN)
+}
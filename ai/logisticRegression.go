@@ -4,6 +4,7 @@ import (
 	v "github.com/deboshire/exp/math/vector"
 	"github.com/deboshire/exp/math/opt/sgrad"
 	"math"
+	"math/rand"
 )
 
 type logisticRegressionClassifier struct {
@@ -11,18 +12,92 @@ type logisticRegressionClassifier struct {
 	theta v.F64
 }
 
+// features[0] is treated as the bias term and is not regularized, so it
+// should be constant 1 (see preprocess.Bias).
 func TrainLogisticRegressionClassifier(
 	features []v.F64,
 	labels v.B,
 	lambda float64,
 	termCrit sgrad.TermCrit,
 	eps float64) BinaryClassifier {
-	y, x := sgrad.Minimize(
+	return TrainLogisticRegressionClassifierWithRand(features, labels, lambda, termCrit, eps, nil)
+}
+
+// Same as TrainLogisticRegressionClassifier, but draws the order of training
+// examples from r instead of the global source.
+func TrainLogisticRegressionClassifierWithRand(
+	features []v.F64,
+	labels v.B,
+	lambda float64,
+	termCrit sgrad.TermCrit,
+	eps float64,
+	r *rand.Rand) BinaryClassifier {
+	y, x := sgrad.MinimizeWithOptions(
 		logisticRegressionCostFunction(features, labels, lambda),
 		v.Zeroes(len(features[0])),
 		eps,
 		termCrit,
-		nil)
+		nil,
+		sgrad.Options{Rand: r})
+
+	return &logisticRegressionClassifier{cost: y, theta: x}
+}
+
+// Binary classifier that can also classify sparse feature vectors.
+type SparseBinaryClassifier interface {
+	BinaryClassifier
+	ClassifySparse(features v.Sparse) (bool, float64)
+}
+
+// Same as TrainLogisticRegressionClassifierWithRand for sparse features.
+// Feature 0 is the bias term and should be stored in every vector. Every
+// step costs the number of stored elements of its example, not the
+// dimension; regularization is applied once per pass, so with lambda > 0
+// results differ slightly from dense training. The resulting classifier
+// accepts both sparse and dense features.
+func TrainSparseLogisticRegressionClassifier(
+	features []v.Sparse,
+	labels v.B,
+	lambda float64,
+	termCrit sgrad.TermCrit,
+	eps float64,
+	r *rand.Rand) SparseBinaryClassifier {
+	y, x := sgrad.MinimizeWithOptions(
+		sparseLogisticRegressionCostFunction(features, labels, lambda),
+		v.Zeroes(features[0].Len()),
+		eps,
+		termCrit,
+		nil,
+		sgrad.Options{Rand: r})
+
+	return &logisticRegressionClassifier{cost: y, theta: x}
+}
+
+// Binary classifier that can also classify single precision feature
+// vectors.
+type F32BinaryClassifier interface {
+	BinaryClassifier
+	ClassifyF32(features v.F32) (bool, float64)
+}
+
+// Same as TrainLogisticRegressionClassifierWithRand for single precision
+// features, which take half the memory. Only features are single
+// precision: sgrad, the weights and all arithmetic stay in double
+// precision. The resulting classifier takes features of either precision.
+func TrainLogisticRegressionClassifierF32(
+	features []v.F32,
+	labels v.B,
+	lambda float64,
+	termCrit sgrad.TermCrit,
+	eps float64,
+	r *rand.Rand) F32BinaryClassifier {
+	y, x := sgrad.MinimizeWithOptions(
+		logisticRegressionCostFunctionF32(features, labels, lambda),
+		v.Zeroes(len(features[0])),
+		eps,
+		termCrit,
+		nil,
+		sgrad.Options{Rand: r})
 
 	return &logisticRegressionClassifier{cost: y, theta: x}
 }
@@ -36,11 +111,32 @@ func NewLogisticRegressionTrainer(
 	}
 }
 
+// Trainers run concurrently must not share termCrit state, so only
+// stateless criteria such as sgrad.NumIterationsCrit should be used with
+// TrainNominalClassifierFromBinaryParallel.
+func NewLogisticRegressionRandTrainer(
+	lambda float64,
+	termCrit sgrad.TermCrit,
+	eps float64) RandBinaryClassifierTrainer {
+	return func(features []v.F64, labels []bool, r *rand.Rand) BinaryClassifier {
+		return TrainLogisticRegressionClassifierWithRand(features, labels, lambda, termCrit, eps, r)
+	}
+}
+
 func sigmoid(x float64) float64 {
 	return 1.0 / (1.0 + math.Exp(-x))
 }
 
+// log(sigmoid(x)) without overflow for large |x|.
+func logSigmoid(x float64) float64 {
+	if x >= 0 {
+		return -math.Log1p(math.Exp(-x))
+	}
+	return x - math.Log1p(math.Exp(x))
+}
+
 // http://mathurl.com/bmfs3db
+// x[0] is the bias weight and is excluded from regularization.
 func logisticRegressionCostFunction(features []v.F64, labels v.B, lambda float64) sgrad.ObjectiveFunc {
 	f := func(idx int, x v.F64, gradient v.F64) (value float64) {
 		feature := features[idx]
@@ -71,7 +167,125 @@ func logisticRegressionCostFunction(features []v.F64, labels v.B, lambda float64
 	return sgrad.ObjectiveFunc{Terms: len(features), F: f}
 }
 
+// Same as logisticRegressionCostFunction, but a step only visits the stored
+// elements of its example. Regularization is applied once per pass instead,
+// as the decay of every update in the pass, and is not included in values.
+func sparseLogisticRegressionCostFunction(features []v.Sparse, labels v.B, lambda float64) sgrad.ObjectiveFunc {
+	f := func(idx int, x v.F64, gradient *v.Sparse) (value float64) {
+		feature := features[idx]
+		z := feature.DotProduct(x)
+		h := sigmoid(z)
+
+		scale := h
+		if labels[idx] {
+			value = -logSigmoid(z)
+			scale = h - 1.0
+		} else {
+			value = -logSigmoid(-z)
+		}
+
+		// Indices are only read, so they can be shared with the feature.
+		gradient.Dim = feature.Dim
+		gradient.Indices = feature.Indices
+		gradient.Values = append(gradient.Values[:0], feature.Values...)
+		gradient.Values.Mul(scale)
+		return
+	}
+
+	regularize := func(x v.F64, alphas []float64) {
+		if lambda == 0.0 {
+			return
+		}
+		decay := 1.0
+		for _, alpha := range alphas {
+			decay *= 1 - alpha*lambda
+		}
+		x[1:].Mul(decay)
+	}
+
+	return sgrad.ObjectiveFunc{Terms: len(features), SparseF: f, EndPass: regularize}
+}
+
+// Same as logisticRegressionCostFunction for single precision features.
+func logisticRegressionCostFunctionF32(features []v.F32, labels v.B, lambda float64) sgrad.ObjectiveFunc {
+	f := func(idx int, x v.F64, gradient v.F64) (value float64) {
+		feature := features[idx]
+		z := feature.DotProductF64(x)
+		h := sigmoid(z)
+
+		scale := h
+		if labels[idx] {
+			value = -logSigmoid(z)
+			scale = h - 1.0
+		} else {
+			value = -logSigmoid(-z)
+		}
+
+		for i, a := range feature {
+			g := scale * float64(a)
+			if i > 0 && lambda != 0.0 {
+				value += 0.5 * lambda * x[i] * x[i]
+				g += lambda * x[i]
+			}
+			gradient[i] = g
+		}
+
+		return
+	}
+
+	return sgrad.ObjectiveFunc{Terms: len(features), F: f}
+}
+
+func (c *logisticRegressionClassifier) ClassifySparse(features v.Sparse) (res bool, confidence float64) {
+	h := sigmoid(features.DotProduct(c.theta))
+	return h >= 0.5, math.Abs(0.5-h) * 2.0
+}
+
+func (c *logisticRegressionClassifier) ClassifyF32(features v.F32) (res bool, confidence float64) {
+	h := sigmoid(features.DotProductF64(c.theta))
+	return h >= 0.5, math.Abs(0.5-h) * 2.0
+}
+
 func (c *logisticRegressionClassifier) Classify(features v.F64) (res bool, confidence float64) {
 	h := sigmoid(c.theta.DotProduct(features))
 	return h >= 0.5, math.Abs(0.5-h) * 2.0
 }
+
+func (c *logisticRegressionClassifier) Probabilities(features v.F64) v.F64 {
+	h := sigmoid(c.theta.DotProduct(features))
+	return v.F64{1 - h, h}
+}
+
+func (c *logisticRegressionClassifier) LogProbabilities(features v.F64) v.F64 {
+	z := c.theta.DotProduct(features)
+	return v.F64{logSigmoid(-z), logSigmoid(z)}
+}
+
+const logisticRegressionModelType = "ai.logisticRegression"
+
+type logisticRegressionState struct {
+	Cost  float64
+	Theta v.F64
+}
+
+func (c *logisticRegressionClassifier) ModelType() string { return logisticRegressionModelType }
+func (c *logisticRegressionClassifier) FeatureDim() int   { return len(c.theta) }
+
+func (c *logisticRegressionClassifier) MarshalModel(e Encoding) ([]byte, error) {
+	return e.Marshal(logisticRegressionState{Cost: c.cost, Theta: c.theta})
+}
+
+func loadLogisticRegression(e Encoding, data []byte) (Model, error) {
+	var s logisticRegressionState
+	if err := e.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if len(s.Theta) == 0 {
+		return nil, errEmptyModel
+	}
+	return &logisticRegressionClassifier{cost: s.Cost, theta: s.Theta}, nil
+}
+
+func init() {
+	RegisterModel(logisticRegressionModelType, loadLogisticRegression)
+}
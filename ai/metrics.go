@@ -0,0 +1,334 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	v "github.com/deboshire/exp/math/vector"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Probabilities are clipped to [logLossEps, 1-logLossEps] to keep log-loss
+// finite.
+const logLossEps = 1e-15
+
+// Classifying as true everything with P(true) >= Threshold gives these rates.
+type ROCPoint struct {
+	Threshold         float64
+	FalsePositiveRate float64
+	TruePositiveRate  float64
+}
+
+type PRPoint struct {
+	Threshold float64
+	Recall    float64
+	Precision float64
+}
+
+// Quality of a binary classifier. Counts and rates are based on the labels
+// returned by the classifier, curves and log-loss on its estimate of P(true).
+type BinaryClassifierReport struct {
+	TruePositives  int
+	FalsePositives int
+	TrueNegatives  int
+	FalseNegatives int
+
+	Accuracy    float64
+	Precision   float64
+	Recall      float64
+	F1          float64
+	Specificity float64
+	LogLoss     float64
+
+	// Curves start at the highest threshold.
+	ROC              []ROCPoint
+	AUC              float64
+	PR               []PRPoint
+	AveragePrecision float64
+}
+
+type scoredExample struct {
+	score float64
+	label bool
+}
+
+type byScoreDesc []scoredExample
+
+func (s byScoreDesc) Len() int           { return len(s) }
+func (s byScoreDesc) Less(i, j int) bool { return s[i].score > s[j].score }
+func (s byScoreDesc) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// a / b, or 0 if b is 0.
+func ratio(a, b int) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+func harmonicMean(a, b float64) float64 {
+	if a+b == 0 {
+		return 0
+	}
+	return 2 * a * b / (a + b)
+}
+
+func EvaluateBinaryClassifierReport(c BinaryClassifier, features []v.F64, labels v.B) *BinaryClassifierReport {
+	r := &BinaryClassifierReport{}
+	examples := make([]scoredExample, len(features))
+	positives := 0
+
+	for i, feature := range features {
+		label := labels[i]
+		result, _ := c.Classify(feature)
+
+		switch {
+		case result && label:
+			r.TruePositives++
+		case result && !label:
+			r.FalsePositives++
+		case !result && label:
+			r.FalseNegatives++
+		default:
+			r.TrueNegatives++
+		}
+
+		p := positiveProbability(c, feature)
+		examples[i] = scoredExample{score: p, label: label}
+		p = math.Min(math.Max(p, logLossEps), 1-logLossEps)
+		if label {
+			positives++
+			r.LogLoss -= math.Log(p)
+		} else {
+			r.LogLoss -= math.Log(1 - p)
+		}
+	}
+
+	if len(features) == 0 {
+		return r
+	}
+
+	negatives := len(features) - positives
+	r.LogLoss /= float64(len(features))
+	r.Accuracy = ratio(r.TruePositives+r.TrueNegatives, len(features))
+	r.Precision = ratio(r.TruePositives, r.TruePositives+r.FalsePositives)
+	r.Recall = ratio(r.TruePositives, positives)
+	r.Specificity = ratio(r.TrueNegatives, negatives)
+	r.F1 = harmonicMean(r.Precision, r.Recall)
+
+	sort.Stable(byScoreDesc(examples))
+
+	r.ROC = []ROCPoint{{Threshold: math.Inf(1)}}
+	r.PR = []PRPoint{{Threshold: math.Inf(1), Precision: 1}}
+	tp, fp := 0, 0
+	for i, e := range examples {
+		if e.label {
+			tp++
+		} else {
+			fp++
+		}
+
+		// Examples with equal scores can only be split all together.
+		if i+1 < len(examples) && examples[i+1].score == e.score {
+			continue
+		}
+
+		roc := ROCPoint{Threshold: e.score, FalsePositiveRate: ratio(fp, negatives), TruePositiveRate: ratio(tp, positives)}
+		prev := r.ROC[len(r.ROC)-1]
+		r.AUC += (roc.FalsePositiveRate - prev.FalsePositiveRate) * (roc.TruePositiveRate + prev.TruePositiveRate) / 2
+		r.ROC = append(r.ROC, roc)
+
+		pr := PRPoint{Threshold: e.score, Recall: ratio(tp, positives), Precision: ratio(tp, tp+fp)}
+		r.AveragePrecision += (pr.Recall - r.PR[len(r.PR)-1].Recall) * pr.Precision
+		r.PR = append(r.PR, pr)
+	}
+
+	return r
+}
+
+func (r *BinaryClassifierReport) String() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "          predicted+  predicted-\n")
+	fmt.Fprintf(&b, "actual+  %10d  %10d\n", r.TruePositives, r.FalseNegatives)
+	fmt.Fprintf(&b, "actual-  %10d  %10d\n", r.FalsePositives, r.TrueNegatives)
+	fmt.Fprintf(&b, "accuracy: %.4f\n", r.Accuracy)
+	fmt.Fprintf(&b, "precision: %.4f\n", r.Precision)
+	fmt.Fprintf(&b, "recall: %.4f\n", r.Recall)
+	fmt.Fprintf(&b, "f1: %.4f\n", r.F1)
+	fmt.Fprintf(&b, "specificity: %.4f\n", r.Specificity)
+	fmt.Fprintf(&b, "log-loss: %.4f\n", r.LogLoss)
+	fmt.Fprintf(&b, "auc: %.4f\n", r.AUC)
+	fmt.Fprintf(&b, "average precision: %.4f\n", r.AveragePrecision)
+	return b.String()
+}
+
+// Quality of a nominal classifier.
+type NominalClassifierReport struct {
+	// Confusion[actual][predicted] is the number of examples of class actual
+	// classified as predicted.
+	Confusion [][]int
+	Accuracy  float64
+
+	// Per class values.
+	Precision []float64
+	Recall    []float64
+	F1        []float64
+	Support   []int
+
+	// Unweighted means of per class values.
+	MacroPrecision float64
+	MacroRecall    float64
+	MacroF1        float64
+
+	// Computed from counts summed over all classes.
+	MicroPrecision float64
+	MicroRecall    float64
+	MicroF1        float64
+
+	// TopKAccuracy[k-1] is the fraction of examples whose class is among k
+	// most probable ones. nil unless the classifier is ProbabilisticClassifier.
+	TopKAccuracy []float64
+}
+
+func EvaluateNominalClassifier(c NominalClassifier, features []v.F64, labels []int, labelsCardinality int) *NominalClassifierReport {
+	r := &NominalClassifierReport{
+		Confusion: make([][]int, labelsCardinality),
+		Precision: make([]float64, labelsCardinality),
+		Recall:    make([]float64, labelsCardinality),
+		F1:        make([]float64, labelsCardinality),
+		Support:   make([]int, labelsCardinality),
+	}
+	for i := range r.Confusion {
+		r.Confusion[i] = make([]int, labelsCardinality)
+	}
+
+	probabilistic, _ := c.(ProbabilisticClassifier)
+	var topK []int
+	if probabilistic != nil {
+		topK = make([]int, labelsCardinality)
+	}
+
+	for i, feature := range features {
+		label := labels[i]
+		result, _ := c.Classify(feature)
+		r.Confusion[label][result]++
+		r.Support[label]++
+
+		if probabilistic != nil {
+			// Ties go to the lower class, as in Classify.
+			p := probabilistic.Probabilities(feature)
+			rank := 0
+			for k, x := range p {
+				if x > p[label] || (x == p[label] && k < label) {
+					rank++
+				}
+			}
+			topK[rank]++
+		}
+	}
+
+	if len(features) == 0 {
+		return r
+	}
+
+	correct := 0
+	for k := 0; k < labelsCardinality; k++ {
+		predicted := 0
+		for actual := 0; actual < labelsCardinality; actual++ {
+			predicted += r.Confusion[actual][k]
+		}
+
+		tp := r.Confusion[k][k]
+		correct += tp
+		r.Precision[k] = ratio(tp, predicted)
+		r.Recall[k] = ratio(tp, r.Support[k])
+		r.F1[k] = harmonicMean(r.Precision[k], r.Recall[k])
+
+		r.MacroPrecision += r.Precision[k] / float64(labelsCardinality)
+		r.MacroRecall += r.Recall[k] / float64(labelsCardinality)
+		r.MacroF1 += r.F1[k] / float64(labelsCardinality)
+	}
+
+	r.Accuracy = ratio(correct, len(features))
+	// Every misclassification is both a false positive and a false negative.
+	r.MicroPrecision = r.Accuracy
+	r.MicroRecall = r.Accuracy
+	r.MicroF1 = r.Accuracy
+
+	if topK != nil {
+		r.TopKAccuracy = make([]float64, labelsCardinality)
+		hits := 0
+		for k := range topK {
+			hits += topK[k]
+			r.TopKAccuracy[k] = ratio(hits, len(features))
+		}
+	}
+
+	return r
+}
+
+func (r *NominalClassifierReport) String() string {
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "actual\\predicted")
+	for k := range r.Confusion {
+		fmt.Fprintf(&b, " %6d", k)
+	}
+	fmt.Fprintln(&b)
+	for actual, row := range r.Confusion {
+		fmt.Fprintf(&b, "%16d", actual)
+		for _, n := range row {
+			fmt.Fprintf(&b, " %6d", n)
+		}
+		fmt.Fprintln(&b)
+	}
+
+	fmt.Fprintf(&b, "class  precision  recall      f1  support\n")
+	for k := range r.Confusion {
+		fmt.Fprintf(&b, "%5d  %9.4f  %6.4f  %6.4f  %7d\n", k, r.Precision[k], r.Recall[k], r.F1[k], r.Support[k])
+	}
+	fmt.Fprintf(&b, "macro  %9.4f  %6.4f  %6.4f\n", r.MacroPrecision, r.MacroRecall, r.MacroF1)
+	fmt.Fprintf(&b, "micro  %9.4f  %6.4f  %6.4f\n", r.MicroPrecision, r.MicroRecall, r.MicroF1)
+
+	fmt.Fprintf(&b, "accuracy: %.4f\n", r.Accuracy)
+	for k, a := range r.TopKAccuracy {
+		fmt.Fprintf(&b, "top-%d accuracy: %.4f\n", k+1, a)
+	}
+	return b.String()
+}
+
+// Writes per class precision, recall, f1, support followed by the row of
+// the confusion matrix.
+func (r *NominalClassifierReport) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+
+	header := []string{"class", "precision", "recall", "f1", "support"}
+	for k := range r.Confusion {
+		header = append(header, fmt.Sprintf("predicted_%d", k))
+	}
+	if err := out.Write(header); err != nil {
+		return err
+	}
+
+	for k, row := range r.Confusion {
+		record := []string{
+			strconv.Itoa(k),
+			strconv.FormatFloat(r.Precision[k], 'g', -1, 64),
+			strconv.FormatFloat(r.Recall[k], 'g', -1, 64),
+			strconv.FormatFloat(r.F1[k], 'g', -1, 64),
+			strconv.Itoa(r.Support[k]),
+		}
+		for _, n := range row {
+			record = append(record, strconv.Itoa(n))
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
@@ -0,0 +1,127 @@
+package ai
+
+import (
+	"bytes"
+	v "github.com/deboshire/exp/math/vector"
+	"math"
+	"strings"
+	"testing"
+)
+
+// Uses features[0] as P(true).
+type scoreClassifier struct{}
+
+func (c scoreClassifier) Classify(features v.F64) (bool, float64) {
+	return features[0] >= 0.5, math.Abs(features[0]-0.5) * 2
+}
+
+func (c scoreClassifier) Probabilities(features v.F64) v.F64 {
+	return v.F64{1 - features[0], features[0]}
+}
+
+func (c scoreClassifier) LogProbabilities(features v.F64) v.F64 {
+	return logs(c.Probabilities(features))
+}
+
+func TestBinaryClassifierReport(t *testing.T) {
+	features := []v.F64{{0.9}, {0.8}, {0.7}, {0.6}, {0.4}, {0.3}}
+	labels := v.B{true, true, false, true, false, false}
+
+	r := EvaluateBinaryClassifierReport(scoreClassifier{}, features, labels)
+
+	if r.TruePositives != 3 || r.FalsePositives != 1 || r.TrueNegatives != 2 || r.FalseNegatives != 0 {
+		t.Errorf("Bad confusion matrix: %+v", r)
+	}
+
+	for _, c := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"accuracy", r.Accuracy, 5.0 / 6},
+		{"precision", r.Precision, 0.75},
+		{"recall", r.Recall, 1},
+		{"f1", r.F1, 6.0 / 7},
+		{"specificity", r.Specificity, 2.0 / 3},
+		{"auc", r.AUC, 8.0 / 9},
+		{"average precision", r.AveragePrecision, 11.0 / 12},
+		{"log-loss", r.LogLoss, -(math.Log(0.9) + math.Log(0.8) + math.Log(0.3) + math.Log(0.6) + math.Log(0.6) + math.Log(0.7)) / 6},
+	} {
+		if math.Abs(c.got-c.want) > 1e-12 {
+			t.Error("Bad", c.name, c.got, "!=", c.want)
+		}
+	}
+
+	if len(r.ROC) != 7 || r.ROC[6].FalsePositiveRate != 1 || r.ROC[6].TruePositiveRate != 1 {
+		t.Error("Bad ROC:", r.ROC)
+	}
+}
+
+func TestBinaryClassifierReportTies(t *testing.T) {
+	features := []v.F64{{0.5}, {0.5}, {0.5}, {0.5}}
+	labels := v.B{true, false, true, false}
+
+	r := EvaluateBinaryClassifierReport(scoreClassifier{}, features, labels)
+
+	if len(r.ROC) != 2 || r.AUC != 0.5 {
+		t.Error("Bad ROC:", r.ROC, "AUC:", r.AUC)
+	}
+}
+
+// Returns features[0] as the class, P(class) = features[1] and spreads
+// the rest uniformly.
+type nominalScoreClassifier struct{}
+
+func (c nominalScoreClassifier) Classify(features v.F64) (int, float64) {
+	return int(features[0]), features[1]
+}
+
+func (c nominalScoreClassifier) Probabilities(features v.F64) v.F64 {
+	p := v.F64{0, 0, 0}
+	for k := range p {
+		p[k] = (1 - features[1]) / 2
+	}
+	p[int(features[0])] = features[1]
+	return p
+}
+
+func (c nominalScoreClassifier) LogProbabilities(features v.F64) v.F64 {
+	return logs(c.Probabilities(features))
+}
+
+func TestNominalClassifierReport(t *testing.T) {
+	features := []v.F64{{0, 0.9}, {0, 0.9}, {1, 0.8}, {1, 0.4}, {2, 0.9}, {2, 0.5}}
+	labels := []int{0, 1, 1, 1, 2, 0}
+
+	r := EvaluateNominalClassifier(nominalScoreClassifier{}, features, labels, 3)
+
+	if r.Confusion[0][0] != 1 || r.Confusion[0][2] != 1 || r.Confusion[1][0] != 1 || r.Confusion[1][1] != 2 || r.Confusion[2][2] != 1 {
+		t.Error("Bad confusion matrix:", r.Confusion)
+	}
+
+	for _, c := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"accuracy", r.Accuracy, 4.0 / 6},
+		{"precision[0]", r.Precision[0], 0.5},
+		{"recall[1]", r.Recall[1], 2.0 / 3},
+		{"f1[2]", r.F1[2], 2.0 / 3},
+		{"macro recall", r.MacroRecall, (0.5 + 2.0/3 + 1) / 3},
+		{"micro f1", r.MicroF1, 4.0 / 6},
+		{"top-1", r.TopKAccuracy[0], 4.0 / 6},
+		{"top-2", r.TopKAccuracy[1], 1},
+		{"top-3", r.TopKAccuracy[2], 1},
+	} {
+		if math.Abs(c.got-c.want) > 1e-12 {
+			t.Error("Bad", c.name, c.got, "!=", c.want)
+		}
+	}
+
+	var b bytes.Buffer
+	if err := r.WriteCSV(&b); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(b.String()), "\n"); len(lines) != 4 || lines[2] != "1,1,0.6666666666666666,0.8,3,1,2,0" {
+		t.Error("Bad csv:", b.String())
+	}
+}
@@ -0,0 +1,306 @@
+/*
+	Feed-forward neural network (multilayer perceptron) classifier.
+
+	Hidden layers share one activation function, the output layer is softmax
+	over the classes. Training minimizes cross-entropy with sgrad, gradients
+	being computed by backpropagation.
+*/
+package mlp
+
+import (
+	"fmt"
+	"github.com/deboshire/exp/ai"
+	"github.com/deboshire/exp/math/opt/sgrad"
+	v "github.com/deboshire/exp/math/vector"
+	"math"
+	"math/rand"
+)
+
+type Activation int
+
+const (
+	Sigmoid Activation = iota
+	Tanh
+	// max(0, x)
+	ReLU
+)
+
+func (a Activation) String() string {
+	switch a {
+	case Sigmoid:
+		return "sigmoid"
+	case Tanh:
+		return "tanh"
+	case ReLU:
+		return "relu"
+	}
+	return fmt.Sprintf("Activation(%d)", int(a))
+}
+
+func (a Activation) apply(z v.F64) {
+	for i, x := range z {
+		switch a {
+		case Sigmoid:
+			z[i] = 1 / (1 + math.Exp(-x))
+		case Tanh:
+			z[i] = math.Tanh(x)
+		case ReLU:
+			z[i] = math.Max(0, x)
+		default:
+			panic(fmt.Sprintf("Unknown activation: %d", a))
+		}
+	}
+}
+
+// Derivative expressed through the activation value y = f(x).
+func (a Activation) derivative(y float64) float64 {
+	switch a {
+	case Sigmoid:
+		return y * (1 - y)
+	case Tanh:
+		return 1 - y*y
+	case ReLU:
+		if y > 0 {
+			return 1
+		}
+		return 0
+	}
+	panic(fmt.Sprintf("Unknown activation: %d", a))
+}
+
+// Network with layer sizes Sizes: Sizes[0] inputs, Sizes[len(Sizes)-1]
+// classes and hidden layers in between.
+type Network struct {
+	Sizes      []int
+	Activation Activation
+	// Weights of all layers one after another. Layer l is a
+	// Sizes[l] x (Sizes[l-1]+1) matrix in row-major order, column 0 holding
+	// the biases.
+	Weights v.F64
+}
+
+type Options struct {
+	// Sizes of hidden layers. No hidden layers gives softmax regression.
+	Hidden     []int
+	Activation Activation
+	// L2 regularization of all weights except biases.
+	Lambda float64
+}
+
+// Creates network with random weights drawn from r (global source if nil):
+// Glorot uniform initialization for sigmoid and tanh, He for ReLU.
+func NewNetwork(sizes []int, activation Activation, r *rand.Rand) *Network {
+	if len(sizes) < 2 {
+		panic(fmt.Sprintf("Network needs at least input and output layers, got sizes %v", sizes))
+	}
+	if r == nil {
+		r = rand.New(rand.NewSource(rand.Int63()))
+	}
+
+	n := &Network{Sizes: append([]int(nil), sizes...), Activation: activation}
+	n.Weights = v.Zeroes(n.NumWeights())
+	for l := 1; l < len(sizes); l++ {
+		in, out := sizes[l-1], sizes[l]
+		w := n.layer(n.Weights, l)
+		for j := 0; j < out; j++ {
+			for k := 1; k <= in; k++ {
+				if activation == ReLU {
+					w[j*(in+1)+k] = r.NormFloat64() * math.Sqrt(2/float64(in))
+				} else {
+					w[j*(in+1)+k] = (2*r.Float64() - 1) * math.Sqrt(6/float64(in+out))
+				}
+			}
+		}
+	}
+	return n
+}
+
+func (n *Network) NumWeights() int {
+	total := 0
+	for l := 1; l < len(n.Sizes); l++ {
+		total += n.Sizes[l] * (n.Sizes[l-1] + 1)
+	}
+	return total
+}
+
+// Weights of layer l within weights laid out as Network.Weights.
+func (n *Network) layer(weights v.F64, l int) v.F64 {
+	start := 0
+	for i := 1; i < l; i++ {
+		start += n.Sizes[i] * (n.Sizes[i-1] + 1)
+	}
+	return weights[start : start+n.Sizes[l]*(n.Sizes[l-1]+1)]
+}
+
+// Outputs of all layers, activations[0] being the input. The last layer is
+// the log-softmax of the output.
+func (n *Network) forward(weights v.F64, features v.F64) []v.F64 {
+	if len(features) != n.Sizes[0] {
+		panic(fmt.Sprintf("Expected %d features, got %d", n.Sizes[0], len(features)))
+	}
+
+	activations := []v.F64{features}
+	last := len(n.Sizes) - 1
+	for l := 1; l <= last; l++ {
+		in := activations[l-1]
+		w := n.layer(weights, l)
+		z := v.Zeroes(n.Sizes[l])
+		for j := range z {
+			row := w[j*(len(in)+1) : (j+1)*(len(in)+1)]
+			z[j] = row[0] + row[1:].DotProduct(in)
+		}
+
+		if l < last {
+			n.Activation.apply(z)
+		} else {
+			logSoftmax(z)
+		}
+		activations = append(activations, z)
+	}
+	return activations
+}
+
+// Replaces z with log(softmax(z)).
+func logSoftmax(z v.F64) {
+	lse := z.LogSumExp()
+	for i := range z {
+		z[i] -= lse
+	}
+}
+
+// Cross-entropy of every training example plus lambda/2 times the squared
+// weights, biases excluded. Gradients are computed by backpropagation.
+func (n *Network) Objective(features []v.F64, labels []int, lambda float64) sgrad.ObjectiveFunc {
+	last := len(n.Sizes) - 1
+
+	f := func(idx int, weights v.F64, gradient v.F64) (value float64) {
+		activations := n.forward(weights, features[idx])
+
+		// Derivatives of the loss by the inputs of the current layer
+		// activation, starting with softmax.
+		delta := activations[last].Copy()
+		for j, logp := range delta {
+			delta[j] = math.Exp(logp)
+		}
+		delta[labels[idx]] -= 1
+		value = -activations[last][labels[idx]]
+
+		for l := last; l >= 1; l-- {
+			in := activations[l-1]
+			w := n.layer(weights, l)
+			g := n.layer(gradient, l)
+
+			for j, d := range delta {
+				row := w[j*(len(in)+1) : (j+1)*(len(in)+1)]
+				grow := g[j*(len(in)+1) : (j+1)*(len(in)+1)]
+				grow[0] = d
+				for k, x := range in {
+					grow[k+1] = d*x + lambda*row[k+1]
+					value += 0.5 * lambda * row[k+1] * row[k+1]
+				}
+			}
+
+			if l == 1 {
+				break
+			}
+			previous := v.Zeroes(len(in))
+			for j, d := range delta {
+				row := w[j*(len(in)+1)+1 : (j+1)*(len(in)+1)]
+				for k := range previous {
+					previous[k] += d * row[k]
+				}
+			}
+			for k, y := range in {
+				previous[k] *= n.Activation.derivative(y)
+			}
+			delta = previous
+		}
+		return
+	}
+
+	return sgrad.ObjectiveFunc{Terms: len(features), F: f}
+}
+
+// Trains network with the given hidden layers. Features should be scaled,
+// e.g. with preprocess.Standardizer; no bias feature is needed.
+func Train(
+	features []v.F64,
+	labels []int,
+	labelsCardinality int,
+	o Options,
+	termCrit sgrad.TermCrit,
+	eps float64,
+	r *rand.Rand) *Network {
+
+	sizes := append([]int{len(features[0])}, o.Hidden...)
+	sizes = append(sizes, labelsCardinality)
+	n := NewNetwork(sizes, o.Activation, r)
+
+	_, n.Weights = sgrad.MinimizeWithOptions(
+		n.Objective(features, labels, o.Lambda),
+		n.Weights,
+		eps,
+		termCrit,
+		nil,
+		sgrad.Options{Rand: r})
+	return n
+}
+
+// Trainers run concurrently must not share termCrit state or r.
+func NewTrainer(o Options, termCrit sgrad.TermCrit, eps float64, r *rand.Rand) ai.NominalClassifierTrainer {
+	return func(features []v.F64, labels []int, labelsCardinality int) ai.NominalClassifier {
+		return Train(features, labels, labelsCardinality, o, termCrit, eps, r)
+	}
+}
+
+func (n *Network) LogProbabilities(features v.F64) v.F64 {
+	activations := n.forward(n.Weights, features)
+	return activations[len(activations)-1]
+}
+
+func (n *Network) Probabilities(features v.F64) v.F64 {
+	p := n.LogProbabilities(features)
+	for i, x := range p {
+		p[i] = math.Exp(x)
+	}
+	return p
+}
+
+// Confidence is the probability of the returned class.
+func (n *Network) Classify(features v.F64) (result int, confidence float64) {
+	p := n.Probabilities(features)
+	result = p.ArgMax()
+	return result, p[result]
+}
+
+const modelType = "mlp.network"
+
+func (n *Network) ModelType() string { return modelType }
+func (n *Network) FeatureDim() int   { return n.Sizes[0] }
+
+func (n *Network) MarshalModel(e ai.Encoding) ([]byte, error) {
+	return e.Marshal(n)
+}
+
+func load(e ai.Encoding, data []byte) (ai.Model, error) {
+	n := &Network{}
+	if err := e.Unmarshal(data, n); err != nil {
+		return nil, err
+	}
+	if len(n.Sizes) < 2 {
+		return nil, fmt.Errorf("Network has %d layers", len(n.Sizes))
+	}
+	for _, size := range n.Sizes {
+		if size <= 0 {
+			return nil, fmt.Errorf("Bad layer sizes %v", n.Sizes)
+		}
+	}
+	if len(n.Weights) != n.NumWeights() {
+		return nil, fmt.Errorf("%d weights for layer sizes %v", len(n.Weights), n.Sizes)
+	}
+	return n, nil
+}
+
+func init() {
+	ai.RegisterModel(modelType, load)
+}
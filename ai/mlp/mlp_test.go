@@ -0,0 +1,76 @@
+package mlp
+
+import (
+	"bytes"
+	"github.com/deboshire/exp/ai"
+	"github.com/deboshire/exp/math/opt/sgrad"
+	v "github.com/deboshire/exp/math/vector"
+	"math/rand"
+	"testing"
+)
+
+// Points in the four quadrants, class 1 where the signs differ: not linearly
+// separable.
+func xor(n int, r *rand.Rand) (features []v.F64, labels []int) {
+	for i := 0; i < n; i++ {
+		x, y := 2*r.Float64()-1, 2*r.Float64()-1
+		features = append(features, v.F64{x, y})
+		if (x > 0) != (y > 0) {
+			labels = append(labels, 1)
+		} else {
+			labels = append(labels, 0)
+		}
+	}
+	return
+}
+
+func TestGradient(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	features := []v.F64{{0.5, -1, 2}, {-0.3, 0.8, 0.1}}
+	labels := []int{2, 0}
+
+	for _, a := range []Activation{Sigmoid, Tanh, ReLU} {
+		n := NewNetwork([]int{3, 5, 4, 3}, a, r)
+		f := n.Objective(features, labels, 0.1)
+		for idx := range features {
+			if err := sgrad.CheckGradient(f, idx, n.Weights, 1e-6); err > 1e-6 {
+				t.Errorf("%v: gradient error %g for term %d", a, err, idx)
+			}
+		}
+	}
+}
+
+func TestXOR(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	features, labels := xor(400, r)
+
+	for _, a := range []Activation{Sigmoid, Tanh, ReLU} {
+		o := Options{Hidden: []int{8}, Activation: a}
+		c := Train(features, labels, 2, o, &sgrad.NumIterationsCrit{NumIterations: 500}, 1e-8, r)
+		if accuracy := ai.EvaluateNominalClassifier(c, features, labels, 2).Accuracy; accuracy < 0.9 {
+			t.Errorf("%v: bad accuracy %g", a, accuracy)
+		}
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	c := NewNetwork([]int{2, 3, 2}, Tanh, rand.New(rand.NewSource(1)))
+
+	for _, e := range []ai.Encoding{ai.JSON, ai.Binary} {
+		var b bytes.Buffer
+		if err := ai.SaveModel(&b, c, e); err != nil {
+			t.Fatal(err)
+		}
+
+		m, err := ai.LoadModel(&b, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		p1 := c.Probabilities(v.F64{0.5, -0.5})
+		p2 := m.(ai.ProbabilisticClassifier).Probabilities(v.F64{0.5, -0.5})
+		if !p1.Eq(p2, 0) {
+			t.Error("Probabilities differ after loading:", p1, p2)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package ai
+
+import (
+	"fmt"
+	v "github.com/deboshire/exp/math/vector"
+	"math"
+)
+
+type NaiveBayesKind int
+
+const (
+	// Features are normally distributed within every class.
+	GaussianNaiveBayes NaiveBayesKind = iota
+	// Features are booleans, x >= 0.5 is true (as in vector.F64.F64ToB).
+	BernoulliNaiveBayes
+	// Features are non-negative counts, e.g. of words in a document.
+	MultinomialNaiveBayes
+)
+
+func (k NaiveBayesKind) String() string {
+	switch k {
+	case GaussianNaiveBayes:
+		return "gaussian"
+	case BernoulliNaiveBayes:
+		return "bernoulli"
+	case MultinomialNaiveBayes:
+		return "multinomial"
+	}
+	return fmt.Sprintf("NaiveBayesKind(%d)", int(k))
+}
+
+type naiveBayesClassifier struct {
+	kind  NaiveBayesKind
+	prior v.F64
+	// Per class parameters of every feature distribution.
+	// Gaussian: mean and variance. Bernoulli: P(true) and unused.
+	// Multinomial: P(feature) and unused.
+	theta []v.F64
+	sigma []v.F64
+
+	// Everything below is derived from the fields above by precompute.
+	logPrior v.F64
+	// Gaussian: 1/(2*variance). Bernoulli, multinomial: log(theta).
+	a []v.F64
+	// Gaussian: normalization constant. Bernoulli: log(1-theta) summed over
+	// all features, so that only true features need to be visited.
+	b v.F64
+	// Bernoulli: log(theta) - log(1-theta).
+	c []v.F64
+}
+
+// Trains naive Bayes classifier. alpha is the Laplace smoothing pseudo-count
+// for Bernoulli and multinomial kinds and must be positive, so that unseen
+// features and empty classes have finite probabilities. For the Gaussian kind
+// alpha times the largest feature variance is added to all variances to keep
+// them positive.
+func TrainNaiveBayesClassifier(
+	features []v.F64,
+	labels []int,
+	labelsCardinality int,
+	kind NaiveBayesKind,
+	alpha float64) NominalClassifier {
+
+	if kind != GaussianNaiveBayes && alpha <= 0 {
+		panic(fmt.Sprintf("Smoothing of %v naive Bayes must be positive, got %g", kind, alpha))
+	}
+
+	dim := len(features[0])
+	c := &naiveBayesClassifier{
+		kind:  kind,
+		prior: v.Zeroes(labelsCardinality),
+		theta: make([]v.F64, labelsCardinality),
+		sigma: make([]v.F64, labelsCardinality),
+	}
+
+	counts := make([]int, labelsCardinality)
+	for k := range c.theta {
+		c.theta[k] = v.Zeroes(dim)
+		c.sigma[k] = v.Zeroes(dim)
+	}
+
+	for i, f := range features {
+		l := labels[i]
+		counts[l]++
+		for j, x := range f {
+			if kind == BernoulliNaiveBayes {
+				if x >= 0.5 {
+					c.theta[l][j]++
+				}
+			} else {
+				c.theta[l][j] += x
+			}
+		}
+	}
+
+	for k, n := range counts {
+		c.prior[k] = float64(n) / float64(len(features))
+	}
+
+	switch kind {
+	case GaussianNaiveBayes:
+		for k, n := range counts {
+			if n > 0 {
+				c.theta[k].Mul(1 / float64(n))
+			}
+		}
+		for i, f := range features {
+			l := labels[i]
+			for j, x := range f {
+				c.sigma[l][j] += (x - c.theta[l][j]) * (x - c.theta[l][j])
+			}
+		}
+
+		smoothing := alpha * maxVariance(features)
+		if smoothing <= 0 {
+			smoothing = 1e-9
+		}
+		for k, n := range counts {
+			for j := range c.sigma[k] {
+				if n > 0 {
+					c.sigma[k][j] /= float64(n)
+				}
+				c.sigma[k][j] += smoothing
+			}
+		}
+
+	case BernoulliNaiveBayes:
+		for k, n := range counts {
+			for j := range c.theta[k] {
+				c.theta[k][j] = (c.theta[k][j] + alpha) / (float64(n) + 2*alpha)
+			}
+		}
+
+	case MultinomialNaiveBayes:
+		for k := range c.theta {
+			total := 0.0
+			for _, x := range c.theta[k] {
+				total += x
+			}
+			for j := range c.theta[k] {
+				c.theta[k][j] = (c.theta[k][j] + alpha) / (total + alpha*float64(dim))
+			}
+		}
+
+	default:
+		panic(fmt.Sprintf("Unknown naive Bayes kind: %d", kind))
+	}
+
+	c.precompute()
+	return c
+}
+
+func NewNaiveBayesTrainer(kind NaiveBayesKind, alpha float64) NominalClassifierTrainer {
+	return func(features []v.F64, labels []int, labelsCardinality int) NominalClassifier {
+		return TrainNaiveBayesClassifier(features, labels, labelsCardinality, kind, alpha)
+	}
+}
+
+func NewNaiveBayesBinaryTrainer(kind NaiveBayesKind, alpha float64) BinaryClassifierTrainer {
+	return NewBinaryTrainerFromNominal(NewNaiveBayesTrainer(kind, alpha))
+}
+
+func maxVariance(features []v.F64) float64 {
+	mean := v.Zeroes(len(features[0]))
+	for _, f := range features {
+		mean.Add(f)
+	}
+	mean.Mul(1 / float64(len(features)))
+
+	variance := v.Zeroes(len(mean))
+	for _, f := range features {
+		for j, x := range f {
+			variance[j] += (x - mean[j]) * (x - mean[j])
+		}
+	}
+
+	max := 0.0
+	for _, x := range variance {
+		max = math.Max(max, x/float64(len(features)))
+	}
+	return max
+}
+
+func (c *naiveBayesClassifier) precompute() {
+	k := len(c.prior)
+	c.logPrior = v.Zeroes(k)
+	c.a = make([]v.F64, k)
+	c.b = v.Zeroes(k)
+	c.c = make([]v.F64, k)
+
+	for l := range c.prior {
+		c.logPrior[l] = math.Log(c.prior[l])
+		c.a[l] = v.Zeroes(len(c.theta[l]))
+		c.c[l] = v.Zeroes(len(c.theta[l]))
+
+		for j, t := range c.theta[l] {
+			switch c.kind {
+			case GaussianNaiveBayes:
+				c.a[l][j] = 1 / (2 * c.sigma[l][j])
+				c.b[l] -= 0.5 * math.Log(2*math.Pi*c.sigma[l][j])
+			case BernoulliNaiveBayes:
+				c.a[l][j] = math.Log(t)
+				c.b[l] += math.Log(1 - t)
+				c.c[l][j] = math.Log(t) - math.Log(1-t)
+			case MultinomialNaiveBayes:
+				c.a[l][j] = math.Log(t)
+			}
+		}
+	}
+}
+
+// Unnormalized log posterior of every class.
+func (c *naiveBayesClassifier) scores(features v.F64) v.F64 {
+	z := c.logPrior.Copy()
+	for l := range z {
+		if math.IsInf(z[l], -1) {
+			continue
+		}
+
+		switch c.kind {
+		case GaussianNaiveBayes:
+			z[l] += c.b[l]
+			for j, x := range features {
+				d := x - c.theta[l][j]
+				z[l] -= d * d * c.a[l][j]
+			}
+		case BernoulliNaiveBayes:
+			z[l] += c.b[l]
+			for j, x := range features {
+				if x >= 0.5 {
+					z[l] += c.c[l][j]
+				}
+			}
+		case MultinomialNaiveBayes:
+			for j, x := range features {
+				if x != 0 {
+					z[l] += x * c.a[l][j]
+				}
+			}
+		}
+	}
+	return z
+}
+
+func (c *naiveBayesClassifier) Probabilities(features v.F64) v.F64 {
+	p := c.scores(features)
+	p.Softmax()
+	return p
+}
+
+func (c *naiveBayesClassifier) LogProbabilities(features v.F64) v.F64 {
+	z := c.scores(features)
+	lse := z.Copy().Softmax()
+	for k := range z {
+		z[k] -= lse
+	}
+	return z
+}
+
+// Confidence is the posterior probability of the returned class.
+func (c *naiveBayesClassifier) Classify(features v.F64) (result int, confidence float64) {
+	p := c.Probabilities(features)
+	for k := range p {
+		if p[k] > p[result] {
+			result = k
+		}
+	}
+	return result, p[result]
+}
+
+const naiveBayesModelType = "ai.naiveBayes"
+
+type naiveBayesState struct {
+	Kind  NaiveBayesKind
+	Prior v.F64
+	Theta []v.F64
+	Sigma []v.F64
+}
+
+func (c *naiveBayesClassifier) ModelType() string { return naiveBayesModelType }
+func (c *naiveBayesClassifier) FeatureDim() int   { return len(c.theta[0]) }
+
+func (c *naiveBayesClassifier) MarshalModel(e Encoding) ([]byte, error) {
+	return e.Marshal(naiveBayesState{Kind: c.kind, Prior: c.prior, Theta: c.theta, Sigma: c.sigma})
+}
+
+// Whether feature parameters are the ones training with positive alpha
+// gives: positive variances and probabilities, below 1 for Bernoulli.
+func smoothed(kind NaiveBayesKind, theta, sigma float64) bool {
+	switch kind {
+	case GaussianNaiveBayes:
+		return sigma > 0
+	case BernoulliNaiveBayes:
+		return theta > 0 && theta < 1
+	}
+	return theta > 0
+}
+
+func loadNaiveBayes(e Encoding, data []byte) (Model, error) {
+	var s naiveBayesState
+	if err := e.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if s.Kind != GaussianNaiveBayes && s.Kind != BernoulliNaiveBayes && s.Kind != MultinomialNaiveBayes {
+		return nil, fmt.Errorf("Unknown naive Bayes kind: %d", s.Kind)
+	}
+	if len(s.Prior) == 0 || len(s.Theta) != len(s.Prior) || len(s.Sigma) != len(s.Prior) || len(s.Theta[0]) == 0 {
+		return nil, errEmptyModel
+	}
+	for k := range s.Theta {
+		if len(s.Theta[k]) != len(s.Theta[0]) || len(s.Sigma[k]) != len(s.Theta[0]) {
+			return nil, fmt.Errorf("Class %d has %d parameters instead of %d", k, len(s.Theta[k]), len(s.Theta[0]))
+		}
+		for j := range s.Theta[k] {
+			if !smoothed(s.Kind, s.Theta[k][j], s.Sigma[k][j]) {
+				return nil, fmt.Errorf("Class %d feature %d has parameters %g, %g not possible with positive smoothing", k, j, s.Theta[k][j], s.Sigma[k][j])
+			}
+		}
+	}
+
+	c := &naiveBayesClassifier{kind: s.Kind, prior: s.Prior, theta: s.Theta, sigma: s.Sigma}
+	c.precompute()
+	return c, nil
+}
+
+func init() {
+	RegisterModel(naiveBayesModelType, loadNaiveBayes)
+}
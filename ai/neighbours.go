@@ -0,0 +1,365 @@
+package ai
+
+import (
+	"container/heap"
+	"fmt"
+	v "github.com/deboshire/exp/math/vector"
+	"math"
+)
+
+// Spatial index used to find nearest neighbours.
+type NeighbourIndex int
+
+const (
+	// KD-tree for low dimensions, ball tree for higher ones and brute force
+	// when the dimension is too high for any tree to prune.
+	AutoIndex NeighbourIndex = iota
+	// Compares query with every point.
+	BruteForceIndex
+	// Splits space by axis-aligned hyperplanes. Efficient for few dimensions.
+	KDTreeIndex
+	// Bounds every subtree by a sphere. Keeps pruning in many dimensions
+	// when the data lies close to a low-dimensional manifold.
+	BallTreeIndex
+)
+
+func (i NeighbourIndex) String() string {
+	switch i {
+	case AutoIndex:
+		return "auto"
+	case BruteForceIndex:
+		return "brute force"
+	case KDTreeIndex:
+		return "kd-tree"
+	case BallTreeIndex:
+		return "ball tree"
+	}
+	return fmt.Sprintf("NeighbourIndex(%d)", int(i))
+}
+
+const (
+	maxKDTreeDim   = 16
+	maxBallTreeDim = 1024
+	// Number of points below which a tree node is scanned linearly.
+	leafSize = 16
+)
+
+type neighbour struct {
+	index int
+	dist2 float64
+}
+
+type neighbourFinder interface {
+	// Returns k points nearest to query, closest first. Points at equal
+	// distance are ordered by index, so all finders agree.
+	nearest(query v.F64, k int) []neighbour
+}
+
+func newNeighbourFinder(features []v.F64, index NeighbourIndex) neighbourFinder {
+	if index == AutoIndex {
+		dim := len(features[0])
+		switch {
+		case len(features) <= leafSize || dim > maxBallTreeDim:
+			index = BruteForceIndex
+		case dim <= maxKDTreeDim:
+			index = KDTreeIndex
+		default:
+			index = BallTreeIndex
+		}
+	}
+
+	switch index {
+	case BruteForceIndex:
+		return bruteForce(features)
+	case KDTreeIndex:
+		return newSpatialTree(features, false)
+	case BallTreeIndex:
+		return newSpatialTree(features, true)
+	}
+	panic(fmt.Sprintf("Unknown neighbour index: %d", index))
+}
+
+// Max-heap of at most k neighbours, the farthest one on top.
+type neighbourHeap struct {
+	k          int
+	neighbours []neighbour
+}
+
+func farther(a, b neighbour) bool {
+	return a.dist2 > b.dist2 || (a.dist2 == b.dist2 && a.index > b.index)
+}
+
+func (h *neighbourHeap) Len() int           { return len(h.neighbours) }
+func (h *neighbourHeap) Less(i, j int) bool { return farther(h.neighbours[i], h.neighbours[j]) }
+func (h *neighbourHeap) Swap(i, j int) {
+	h.neighbours[i], h.neighbours[j] = h.neighbours[j], h.neighbours[i]
+}
+func (h *neighbourHeap) Push(x interface{}) { h.neighbours = append(h.neighbours, x.(neighbour)) }
+func (h *neighbourHeap) Pop() interface{} {
+	last := h.neighbours[len(h.neighbours)-1]
+	h.neighbours = h.neighbours[:len(h.neighbours)-1]
+	return last
+}
+
+func (h *neighbourHeap) full() bool {
+	return len(h.neighbours) >= h.k
+}
+
+// Squared distance that a new neighbour has to beat, +Inf until k are found.
+func (h *neighbourHeap) bound() float64 {
+	if !h.full() {
+		return math.Inf(1)
+	}
+	return h.neighbours[0].dist2
+}
+
+func (h *neighbourHeap) add(n neighbour) {
+	if !h.full() {
+		heap.Push(h, n)
+	} else if farther(h.neighbours[0], n) {
+		h.neighbours[0] = n
+		heap.Fix(h, 0)
+	}
+}
+
+// Empties the heap, closest neighbour first.
+func (h *neighbourHeap) sorted() []neighbour {
+	result := make([]neighbour, len(h.neighbours))
+	for i := len(result) - 1; i >= 0; i-- {
+		result[i] = heap.Pop(h).(neighbour)
+	}
+	return result
+}
+
+type bruteForce []v.F64
+
+func (b bruteForce) nearest(query v.F64, k int) []neighbour {
+	h := &neighbourHeap{k: k}
+	for i, f := range b {
+		h.add(neighbour{i, query.Dist2(f)})
+	}
+	return h.sorted()
+}
+
+// KD-tree or ball tree. KD-tree splits points at the median of the
+// coordinate with the largest spread, ball tree at the median of their
+// projections on the line through two distant points.
+type spatialTree struct {
+	features []v.F64
+	// Point indexes, every node owning a contiguous range.
+	order []int
+	nodes []treeNode
+	ball  bool
+}
+
+type treeNode struct {
+	start, end int
+	// Children indexes in nodes, 0 for leaves.
+	left, right int
+
+	// KD-tree: points of the left child have coordinate splitDim <= split,
+	// of the right child >= split.
+	splitDim int
+	split    float64
+
+	// Ball tree: all points are within radius of center.
+	center v.F64
+	radius float64
+}
+
+func newSpatialTree(features []v.F64, ball bool) *spatialTree {
+	t := &spatialTree{features: features, order: make([]int, len(features)), ball: ball}
+	for i := range t.order {
+		t.order[i] = i
+	}
+	t.build(0, len(features))
+	return t
+}
+
+// Builds the node for points order[start:end] and returns its index.
+func (t *spatialTree) build(start, end int) int {
+	id := len(t.nodes)
+	t.nodes = append(t.nodes, treeNode{start: start, end: end})
+	node := treeNode{start: start, end: end}
+
+	if t.ball {
+		node.center, node.radius = t.boundingSphere(start, end)
+	}
+
+	if end-start > leafSize {
+		var key func(i int) float64
+		if t.ball {
+			key = t.spreadDirection(start, end, node.center)
+		} else {
+			node.splitDim = t.widestDim(start, end)
+			key = func(i int) float64 { return t.features[i][node.splitDim] }
+		}
+
+		mid := (start + end) / 2
+		t.selectNth(start, end, mid, key)
+		node.split = key(t.order[mid])
+		node.left = t.build(start, mid)
+		node.right = t.build(mid, end)
+	}
+
+	t.nodes[id] = node
+	return id
+}
+
+func (t *spatialTree) boundingSphere(start, end int) (center v.F64, radius float64) {
+	center = v.Zeroes(len(t.features[0]))
+	for _, i := range t.order[start:end] {
+		center.Add(t.features[i])
+	}
+	center.Mul(1 / float64(end-start))
+
+	for _, i := range t.order[start:end] {
+		radius = math.Max(radius, center.Dist2(t.features[i]))
+	}
+	return center, math.Sqrt(radius)
+}
+
+// Projection on the direction from the point farthest from center to the
+// point farthest from that one, a cheap approximation of the direction of
+// the largest spread.
+func (t *spatialTree) spreadDirection(start, end int, center v.F64) func(i int) float64 {
+	farthest := func(from v.F64) v.F64 {
+		result, max := from, -1.0
+		for _, i := range t.order[start:end] {
+			if d := from.Dist2(t.features[i]); d > max {
+				result, max = t.features[i], d
+			}
+		}
+		return result
+	}
+
+	a := farthest(center)
+	direction := farthest(a).Copy()
+	direction.Sub(a)
+	return func(i int) float64 { return direction.DotProduct(t.features[i]) }
+}
+
+func (t *spatialTree) widestDim(start, end int) int {
+	min := t.features[t.order[start]].Copy()
+	max := min.Copy()
+	for _, i := range t.order[start:end] {
+		for d, x := range t.features[i] {
+			min[d] = math.Min(min[d], x)
+			max[d] = math.Max(max[d], x)
+		}
+	}
+
+	result := 0
+	for d := range min {
+		if max[d]-min[d] > max[result]-min[result] {
+			result = d
+		}
+	}
+	return result
+}
+
+// Partially sorts order[start:end] by key of the point so that order[n] is
+// in its sorted position (quickselect).
+func (t *spatialTree) selectNth(start, end, n int, key func(i int) float64) {
+	x := func(i int) float64 { return key(t.order[i]) }
+
+	swap := func(i, j int) { t.order[i], t.order[j] = t.order[j], t.order[i] }
+
+	for end-start > 1 {
+		// Median of three pivot.
+		a, b, c := x(start), x((start+end)/2), x(end-1)
+		pivot := math.Max(math.Min(a, b), math.Min(math.Max(a, b), c))
+
+		// Three-way partition into [start, lt) < pivot, [lt, gt) == pivot
+		// and [gt, end) > pivot, so that repeated values do not make it
+		// quadratic.
+		lt, i, gt := start, start, end
+		for i < gt {
+			switch y := x(i); {
+			case y < pivot:
+				swap(i, lt)
+				lt++
+				i++
+			case y > pivot:
+				gt--
+				swap(i, gt)
+			default:
+				i++
+			}
+		}
+
+		switch {
+		case n < lt:
+			end = lt
+		case n >= gt:
+			start = gt
+		default:
+			return
+		}
+	}
+}
+
+func (t *spatialTree) nearest(query v.F64, k int) []neighbour {
+	h := &neighbourHeap{k: k}
+	if t.ball {
+		t.searchBall(0, query, query.Dist2(t.nodes[0].center), h)
+	} else {
+		t.searchKD(0, query, h)
+	}
+	return h.sorted()
+}
+
+func (t *spatialTree) searchLeaf(node *treeNode, query v.F64, h *neighbourHeap) {
+	for _, i := range t.order[node.start:node.end] {
+		h.add(neighbour{i, query.Dist2(t.features[i])})
+	}
+}
+
+func (t *spatialTree) searchKD(id int, query v.F64, h *neighbourHeap) {
+	node := &t.nodes[id]
+	if node.left == 0 {
+		t.searchLeaf(node, query, h)
+		return
+	}
+
+	diff := query[node.splitDim] - node.split
+	near, far := node.left, node.right
+	if diff > 0 {
+		near, far = far, near
+	}
+	t.searchKD(near, query, h)
+	// Equal bound still has to be searched: a point at the same distance
+	// may win on index.
+	if diff*diff <= h.bound() {
+		t.searchKD(far, query, h)
+	}
+}
+
+// centerDist2 is the squared distance from query to the node center.
+func (t *spatialTree) searchBall(id int, query v.F64, centerDist2 float64, h *neighbourHeap) {
+	node := &t.nodes[id]
+	// Lower bound of the distance from query to points of the node, less a
+	// margin for rounding of the square roots: points exactly at the bound
+	// still have to be searched, as they may win on index.
+	centerDist := math.Sqrt(centerDist2)
+	d := centerDist - node.radius - 1e-12*(centerDist+node.radius)
+	if d > 0 && d*d > h.bound() {
+		return
+	}
+
+	if node.left == 0 {
+		t.searchLeaf(node, query, h)
+		return
+	}
+
+	// Closer child first tightens the bound sooner.
+	near, far := node.left, node.right
+	nearDist2 := query.Dist2(t.nodes[near].center)
+	farDist2 := query.Dist2(t.nodes[far].center)
+	if farDist2 < nearDist2 {
+		near, far = far, near
+		nearDist2, farDist2 = farDist2, nearDist2
+	}
+	t.searchBall(near, query, nearDist2, h)
+	t.searchBall(far, query, farDist2, h)
+}
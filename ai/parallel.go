@@ -0,0 +1,85 @@
+package ai
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Error reported when one of concurrently run training tasks panics.
+type TrainingError struct {
+	// Index of the failed task, e.g. the class of a one-vs-rest classifier.
+	Task int
+	// Value passed to panic.
+	Value interface{}
+}
+
+func (e *TrainingError) Error() string {
+	return fmt.Sprintf("training task %d failed: %v", e.Task, e.Value)
+}
+
+// Runs task(0) ... task(n-1) on at most concurrency goroutines, GOMAXPROCS
+// if concurrency <= 0.
+//
+// Every task gets its own random source. Seeds are drawn from r (global
+// source if nil) in task order before anything starts, so results do not
+// depend on scheduling. As soon as a task panics no new tasks are started
+// and the *TrainingError of the failed task with the lowest index is
+// returned.
+func runConcurrently(n int, concurrency int, r *rand.Rand, task func(i int, r *rand.Rand)) error {
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+
+	seeds := make([]int64, n)
+	for i := range seeds {
+		if r == nil {
+			seeds[i] = rand.Int63()
+		} else {
+			seeds[i] = r.Int63()
+		}
+	}
+
+	errs := make([]error, n)
+	tasks := make(chan int)
+	var failed int32
+	var wg sync.WaitGroup
+
+	for w := 0; w < concurrency && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range tasks {
+				if errs[i] = runTask(i, seeds[i], task); errs[i] != nil {
+					atomic.StoreInt32(&failed, 1)
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < n && atomic.LoadInt32(&failed) == 0; i++ {
+		tasks <- i
+	}
+	close(tasks)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runTask(i int, seed int64, task func(i int, r *rand.Rand)) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = &TrainingError{Task: i, Value: p}
+		}
+	}()
+
+	task(i, rand.New(rand.NewSource(seed)))
+	return nil
+}
@@ -0,0 +1,217 @@
+/*
+	Principal component analysis.
+
+	PCA implements preprocess.Transformer, so it can reduce features in a
+	pipeline and be saved with a classifier.
+*/
+package pca
+
+import (
+	"fmt"
+	"github.com/deboshire/exp/ai"
+	"github.com/deboshire/exp/ai/preprocess"
+	"github.com/deboshire/exp/math/matrix"
+	v "github.com/deboshire/exp/math/vector"
+	"math"
+)
+
+type Method int
+
+const (
+	// Eigen-decomposition of the covariance matrix, fast when there are
+	// many more examples than features.
+	CovarianceMethod Method = iota
+	// Singular value decomposition of centered data, more accurate for
+	// components with small variance.
+	SVDMethod
+)
+
+func (m Method) String() string {
+	switch m {
+	case CovarianceMethod:
+		return "covariance"
+	case SVDMethod:
+		return "svd"
+	}
+	return fmt.Sprintf("Method(%d)", int(m))
+}
+
+type Options struct {
+	// Number of components to keep, all if 0.
+	Components int
+	// If positive, keeps the fewest components explaining at least this
+	// fraction of variance instead.
+	VarianceRatio float64
+	Method        Method
+	// Scales components to unit variance.
+	Whiten bool
+}
+
+// Projection of centered features onto directions of largest variance.
+type PCA struct {
+	Options Options
+	Mean    v.F64
+	// Principal axes of unit length, in order of decreasing variance.
+	Components []v.F64
+	// Variance of training data along every kept component.
+	Variances v.F64
+	// Fraction of total variance explained by every kept component.
+	VarianceRatios v.F64
+}
+
+func New(o Options) *PCA {
+	return &PCA{Options: o}
+}
+
+func (p *PCA) Fit(features []v.F64) {
+	if len(features) == 0 {
+		panic("No training data")
+	}
+	dim := len(features[0])
+	n := float64(len(features))
+
+	p.Mean = v.Zeroes(dim)
+	for _, f := range features {
+		p.Mean.Add(f)
+	}
+	p.Mean.Mul(1 / n)
+
+	var variances v.F64
+	var axes *matrix.Dense
+	switch p.Options.Method {
+	case CovarianceMethod:
+		// Lower triangle only, NewSymmetricEigen does not look above.
+		cov := v.Zeroes(dim * dim)
+		d := v.Zeroes(dim)
+		for _, f := range features {
+			f.CopyTo(d)
+			d.Sub(p.Mean)
+			for i := 0; i < dim; i++ {
+				for j := 0; j <= i; j++ {
+					cov[i*dim+j] += d[i] * d[j]
+				}
+			}
+		}
+		cov.Mul(1 / n)
+		e := matrix.NewSymmetricEigen(matrix.NewFromData(dim, dim, cov))
+		variances, axes = e.Values, e.Vectors
+		for i, x := range variances {
+			// Rounding can make zero eigenvalues slightly negative.
+			variances[i] = math.Max(0, x)
+		}
+
+	case SVDMethod:
+		centered := matrix.FromRows(features)
+		for i := 0; i < len(features); i++ {
+			centered.RowView(i).Sub(matrix.NewFromData(1, dim, p.Mean))
+		}
+		s := matrix.NewSVD(centered)
+		variances, axes = s.Values, s.V
+		for i, x := range variances {
+			variances[i] = x * x / n
+		}
+
+	default:
+		panic(fmt.Sprintf("Unknown PCA method: %d", p.Options.Method))
+	}
+
+	total := variances.Sum()
+	ratios := v.Zeroes(dim)
+	for i, x := range variances {
+		if total > 0 {
+			ratios[i] = x / total
+		}
+	}
+
+	k := dim
+	if p.Options.VarianceRatio > 0 {
+		explained := 0.0
+		for k = 0; k < dim && explained < p.Options.VarianceRatio; k++ {
+			explained += ratios[k]
+		}
+	} else if p.Options.Components > 0 && p.Options.Components < dim {
+		k = p.Options.Components
+	}
+
+	p.Components = make([]v.F64, k)
+	for i := range p.Components {
+		p.Components[i] = axes.Col(i)
+	}
+	p.Variances = variances[:k]
+	p.VarianceRatios = ratios[:k]
+}
+
+// Scale of whitened component i, 0 for components without variance.
+func (p *PCA) whitening(i int) float64 {
+	if !p.Options.Whiten {
+		return 1
+	}
+	if p.Variances[i] == 0 {
+		return 0
+	}
+	return 1 / math.Sqrt(p.Variances[i])
+}
+
+func (p *PCA) Transform(features v.F64) v.F64 {
+	if len(features) != len(p.Mean) {
+		panic(fmt.Sprintf("Expected %d features, got %d", len(p.Mean), len(features)))
+	}
+	d := features.Copy()
+	d.Sub(p.Mean)
+
+	result := v.Zeroes(len(p.Components))
+	for i, c := range p.Components {
+		result[i] = c.DotProduct(d) * p.whitening(i)
+	}
+	return result
+}
+
+// Features whose projection is the given one, the nearest to the original
+// features if components were dropped.
+func (p *PCA) InverseTransform(projection v.F64) v.F64 {
+	if len(projection) != len(p.Components) {
+		panic(fmt.Sprintf("Expected %d components, got %d", len(p.Components), len(projection)))
+	}
+	result := p.Mean.Copy()
+	for i, c := range p.Components {
+		x := projection[i]
+		if p.Options.Whiten {
+			x *= math.Sqrt(p.Variances[i])
+		}
+		for j, cj := range c {
+			result[j] += x * cj
+		}
+	}
+	return result
+}
+
+func (p *PCA) InputDim() int  { return len(p.Mean) }
+func (p *PCA) OutputDim() int { return len(p.Components) }
+
+const transformerType = "pca.pca"
+
+func (p *PCA) TransformerType() string { return transformerType }
+
+func (p *PCA) MarshalTransformer(e ai.Encoding) ([]byte, error) {
+	return e.Marshal(p)
+}
+
+func load(e ai.Encoding, data []byte) (preprocess.Transformer, error) {
+	p := &PCA{}
+	if err := e.Unmarshal(data, p); err != nil {
+		return nil, err
+	}
+	if len(p.Variances) != len(p.Components) {
+		return nil, fmt.Errorf("%d variances for %d components", len(p.Variances), len(p.Components))
+	}
+	for _, c := range p.Components {
+		if len(c) != len(p.Mean) {
+			return nil, fmt.Errorf("Component of dimension %d for %d features", len(c), len(p.Mean))
+		}
+	}
+	return p, nil
+}
+
+func init() {
+	preprocess.RegisterTransformer(transformerType, load)
+}
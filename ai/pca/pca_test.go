@@ -0,0 +1,110 @@
+package pca
+
+import (
+	"github.com/deboshire/exp/ai"
+	"github.com/deboshire/exp/ai/preprocess"
+	v "github.com/deboshire/exp/math/vector"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Points near the plane spanned by (1, 1, 0) and (0, 0, 1), with variances
+// 9, 1 and 0.01 along (1, 1, 0)/sqrt(2), (0, 0, 1) and (1, -1, 0)/sqrt(2).
+func plane(n int) (points []v.F64) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < n; i++ {
+		a, b, c := 3*r.NormFloat64(), r.NormFloat64(), 0.1*r.NormFloat64()
+		points = append(points, v.F64{5 + (a+c)/math.Sqrt2, -2 + (a-c)/math.Sqrt2, b})
+	}
+	return
+}
+
+func sameUpToSign(a, b v.F64, eps float64) bool {
+	if a.DotProduct(b) < 0 {
+		b = b.Copy()
+		b.Mul(-1)
+	}
+	return a.Eq(b, eps)
+}
+
+func TestPCA(t *testing.T) {
+	points := plane(2000)
+
+	byMethod := make(map[Method]*PCA)
+	for _, method := range []Method{CovarianceMethod, SVDMethod} {
+		p := New(Options{Method: method})
+		p.Fit(points)
+		byMethod[method] = p
+
+		if !p.Variances.Eq(v.F64{9, 1, 0.01}, 0.1*p.Variances[0]) {
+			t.Errorf("%v: variances %v", method, p.Variances)
+		}
+		if !sameUpToSign(p.Components[0], v.F64{math.Sqrt(0.5), math.Sqrt(0.5), 0}, 0.02) ||
+			!sameUpToSign(p.Components[1], v.F64{0, 0, 1}, 0.02) {
+			t.Errorf("%v: components %v", method, p.Components)
+		}
+		if total := p.VarianceRatios.DotProduct(v.F64{1, 1, 1}); math.Abs(total-1) > 1e-12 {
+			t.Errorf("%v: variance ratios %v", method, p.VarianceRatios)
+		}
+
+		for _, x := range points[:10] {
+			if back := p.InverseTransform(p.Transform(x)); !back.Eq(x, 1e-9) {
+				t.Errorf("%v: %v transformed back to %v", method, x, back)
+			}
+		}
+	}
+
+	eigen, svd := byMethod[CovarianceMethod], byMethod[SVDMethod]
+	if !eigen.Variances.Eq(svd.Variances, 1e-9) {
+		t.Errorf("Variances differ: %v %v", eigen.Variances, svd.Variances)
+	}
+	for i := range eigen.Components {
+		if !sameUpToSign(eigen.Components[i], svd.Components[i], 1e-6) {
+			t.Errorf("Components differ: %v %v", eigen.Components[i], svd.Components[i])
+		}
+	}
+}
+
+func TestReduce(t *testing.T) {
+	points := plane(2000)
+
+	p := New(Options{VarianceRatio: 0.95})
+	p.Fit(points)
+	if p.OutputDim() != 2 || p.InputDim() != 3 {
+		t.Fatalf("Dimensions %d -> %d", p.InputDim(), p.OutputDim())
+	}
+
+	// Dropped component is small.
+	for _, x := range points[:10] {
+		if back := p.InverseTransform(p.Transform(x)); !back.Eq(x, 0.5) {
+			t.Errorf("%v reconstructed as %v", x, back)
+		}
+	}
+
+	white := New(Options{Components: 2, Whiten: true})
+	projected := preprocess.FitTransform(white, points)
+	for i := 0; i < 2; i++ {
+		variance := 0.0
+		for _, x := range projected {
+			variance += x[i] * x[i]
+		}
+		if variance /= float64(len(projected)); math.Abs(variance-1) > 1e-9 {
+			t.Errorf("Variance of whitened component %d: %g", i, variance)
+		}
+	}
+
+	for _, e := range []ai.Encoding{ai.JSON, ai.Binary} {
+		data, err := preprocess.EncodeTransformer(white, e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := preprocess.DecodeTransformer(data, e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if x, y := white.Transform(points[0]), loaded.Transform(points[0]); !x.Eq(y, 0) {
+			t.Errorf("Transform differs after loading: %v %v", x, y)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Version of the saved model envelope. Models saved by newer versions are
+// rejected.
+const modelFormatVersion = 1
+
+// Prefix of binary encoded models, distinguishes them from JSON.
+var binaryModelMagic = []byte("EXPMODEL")
+
+// Encoding of model parameters.
+type Encoding interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type jsonEncoding struct{}
+
+func (jsonEncoding) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonEncoding) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+type gobEncoding struct{}
+
+func (gobEncoding) Marshal(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	err := gob.NewEncoder(&b).Encode(v)
+	return b.Bytes(), err
+}
+
+func (gobEncoding) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+var (
+	// Human readable encoding.
+	JSON Encoding = jsonEncoding{}
+	// Compact encoding based on encoding/gob.
+	Binary Encoding = gobEncoding{}
+)
+
+// Classifier that can be saved and loaded back.
+type Model interface {
+	// Name the model type was registered with by RegisterModel.
+	ModelType() string
+	// Number of features the model expects, 0 if unknown.
+	FeatureDim() int
+	// Encodes model parameters. Nested models are encoded with EncodeModel.
+	MarshalModel(e Encoding) ([]byte, error)
+}
+
+// Restores a model from parameters produced by its MarshalModel.
+type ModelLoader func(e Encoding, data []byte) (Model, error)
+
+var modelLoaders = make(map[string]ModelLoader)
+
+// Makes models of the given type loadable. Should be called from init.
+func RegisterModel(modelType string, loader ModelLoader) {
+	if _, ok := modelLoaders[modelType]; ok {
+		panic(fmt.Sprintf("Model type %s is already registered", modelType))
+	}
+	modelLoaders[modelType] = loader
+}
+
+type savedModel struct {
+	Version    int
+	Type       string
+	FeatureDim int
+	Data       json.RawMessage
+}
+
+// Encodes a model together with its type, so that DecodeModel can restore
+// the right concrete type.
+func EncodeModel(m Model, e Encoding) ([]byte, error) {
+	data, err := m.MarshalModel(e)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.Marshal(savedModel{
+		Version:    modelFormatVersion,
+		Type:       m.ModelType(),
+		FeatureDim: m.FeatureDim(),
+		Data:       data,
+	})
+}
+
+func DecodeModel(data []byte, e Encoding) (Model, error) {
+	var saved savedModel
+	if err := e.Unmarshal(data, &saved); err != nil {
+		return nil, err
+	}
+
+	if saved.Version < 1 || saved.Version > modelFormatVersion {
+		return nil, fmt.Errorf("Unsupported model version %d", saved.Version)
+	}
+
+	loader, ok := modelLoaders[saved.Type]
+	if !ok {
+		return nil, fmt.Errorf("Unknown model type %q", saved.Type)
+	}
+
+	m, err := loader(e, saved.Data)
+	if err != nil {
+		return nil, fmt.Errorf("Can't load %s: %v", saved.Type, err)
+	}
+
+	if m.FeatureDim() != saved.FeatureDim {
+		return nil, fmt.Errorf("%s expects %d features, saved as %d", saved.Type, m.FeatureDim(), saved.FeatureDim)
+	}
+	return m, nil
+}
+
+// Nested models must have the same number of features as their parent.
+func decodeNestedModel(data []byte, e Encoding, featureDim int) (Model, error) {
+	m, err := DecodeModel(data, e)
+	if err != nil {
+		return nil, err
+	}
+	if m.FeatureDim() != featureDim {
+		return nil, fmt.Errorf("Nested %s expects %d features instead of %d", m.ModelType(), m.FeatureDim(), featureDim)
+	}
+	return m, nil
+}
+
+// Classifiers composed of other classifiers can only be saved if all
+// of them are models.
+func asModel(c interface{}) (Model, error) {
+	m, ok := c.(Model)
+	if !ok {
+		return nil, fmt.Errorf("%T can not be saved", c)
+	}
+	return m, nil
+}
+
+func SaveModel(w io.Writer, m Model, e Encoding) error {
+	data, err := EncodeModel(m, e)
+	if err != nil {
+		return err
+	}
+
+	if e == Binary {
+		if _, err := w.Write(binaryModelMagic); err != nil {
+			return err
+		}
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Loads a model saved with any encoding. The model is rejected unless it
+// expects featureDim features; featureDim <= 0 accepts any model.
+func LoadModel(r io.Reader, featureDim int) (Model, error) {
+	reader := bufio.NewReader(r)
+
+	var e Encoding = JSON
+	if magic, err := reader.Peek(len(binaryModelMagic)); err == nil && bytes.Equal(magic, binaryModelMagic) {
+		e = Binary
+		reader.Discard(len(binaryModelMagic))
+	}
+
+	var b bytes.Buffer
+	if _, err := b.ReadFrom(reader); err != nil {
+		return nil, err
+	}
+
+	m, err := DecodeModel(b.Bytes(), e)
+	if err != nil {
+		return nil, err
+	}
+
+	if featureDim > 0 && m.FeatureDim() != featureDim {
+		return nil, fmt.Errorf("Model expects %d features instead of %d", m.FeatureDim(), featureDim)
+	}
+	return m, nil
+}
+
+var errEmptyModel = errors.New("Model has no parameters")
@@ -0,0 +1,90 @@
+package ai
+
+import (
+	"bytes"
+	"github.com/deboshire/exp/math/opt/sgrad"
+	v "github.com/deboshire/exp/math/vector"
+	"strings"
+	"testing"
+)
+
+func TestSaveLoadModel(t *testing.T) {
+	features, labels := clusters(60)
+	trainer := testTrainer()
+	models := []Model{
+		trainer(features[:3], []bool{true, false, false}).(Model),
+		TrainNominalClassifierFromBinary(features, labels, 3, trainer).(Model),
+		TrainNominalClassifierOneVsOne(features, labels, 3, trainer).(Model),
+		TrainSoftmaxRegressionClassifier(features, labels, 3, 0, &sgrad.NumIterationsCrit{NumIterations: 5}, 1e-8).(Model),
+		TrainNaiveBayesClassifier(features, labels, 3, GaussianNaiveBayes, 1e-2).(Model),
+		NewNaiveBayesBinaryTrainer(BernoulliNaiveBayes, 1)(features, v.B(make([]bool, len(features)))).(Model),
+		TrainKNNClassifier(features, labels, 3, 5, true, KDTreeIndex).(Model),
+		TrainDecisionTree(features, labels, 3, DecisionTreeOptions{}),
+		forest(features, labels),
+		TrainLinearSVMClassifier(features[:3], []bool{true, false, false}, LinearSVMOptions{Lambda: 0.1, PlattScaling: true}, &sgrad.NumIterationsCrit{NumIterations: 5}, 1e-8).(Model),
+		TrainAdaBoostClassifier(features, v.B(make([]bool, len(features))), NewDecisionStumpTrainer(GiniCriterion), AdaBoostOptions{Kind: RealAdaBoost, Rounds: 3}),
+		TrainAdaBoostClassifier(features, oneVsRest(labels, 1), negatedStumpTrainer, AdaBoostOptions{Rounds: 3}),
+	}
+
+	for _, m := range models {
+		for _, e := range []Encoding{JSON, Binary} {
+			var b bytes.Buffer
+			if err := SaveModel(&b, m, e); err != nil {
+				t.Fatal(m.ModelType(), err)
+			}
+
+			loaded, err := LoadModel(bytes.NewReader(b.Bytes()), 3)
+			if err != nil {
+				t.Fatal(m.ModelType(), err)
+			}
+
+			p1 := m.(ProbabilisticClassifier).Probabilities(features[1])
+			p2 := loaded.(ProbabilisticClassifier).Probabilities(features[1])
+			if !p1.Eq(p2, 0) {
+				t.Error(m.ModelType(), "probabilities differ after loading:", p1, p2)
+			}
+
+			if _, err := LoadModel(bytes.NewReader(b.Bytes()), 4); err == nil {
+				t.Error(m.ModelType(), "loaded with wrong number of features")
+			}
+		}
+	}
+}
+
+func forest(features []v.F64, labels []int) Model {
+	c, err := TrainRandomForest(features, labels, 3, RandomForestOptions{EnsembleOptions: EnsembleOptions{Size: 3}}, nil)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func TestLoadModelErrors(t *testing.T) {
+	for _, c := range []struct{ data, err string }{
+		{`{"Version":1,"Type":"unknown","FeatureDim":1,"Data":{}}`, "Unknown model type"},
+		{`{"Version":2,"Type":"ai.logisticRegression","FeatureDim":1,"Data":{}}`, "Unsupported model version"},
+		{`{"Type":"ai.logisticRegression","FeatureDim":1,"Data":{}}`, "Unsupported model version"},
+		{`{"Version":1,"Type":"ai.logisticRegression","FeatureDim":3,"Data":{"Theta":[1,2]}}`, "expects 2 features"},
+		{`{"Version":1,"Type":"ai.naiveBayes","FeatureDim":1,"Data":{"Kind":3,"Prior":[1],"Theta":[[0.5]],"Sigma":[[0]]}}`, "Unknown naive Bayes kind"},
+		{`{"Version":1,"Type":"ai.naiveBayes","FeatureDim":1,"Data":{"Kind":1,"Prior":[1],"Theta":[[1]],"Sigma":[[0]]}}`, "positive smoothing"},
+		{`{"Version":1,"Type":"ai.oneVsRest","FeatureDim":2,"Data":{"Classifiers":[` +
+			`{"Version":1,"Type":"ai.logisticRegression","FeatureDim":2,"Data":{"Theta":[1,2]}},` +
+			`{"Version":1,"Type":"ai.logisticRegression","FeatureDim":3,"Data":{"Theta":[1,2,3]}}]}}`, "Nested"},
+	} {
+		_, err := LoadModel(strings.NewReader(c.data), 0)
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("Expected %q error, got %v", c.err, err)
+		}
+	}
+}
+
+type notAModel struct{}
+
+func (notAModel) Classify(features v.F64) (bool, float64) { return true, 1 }
+
+func TestSaveNonModel(t *testing.T) {
+	c := &compoundNominalClassifier{classifiers: []BinaryClassifier{notAModel{}}}
+	if err := SaveModel(&bytes.Buffer{}, c, JSON); err == nil {
+		t.Error("Saved classifier composed of non-models")
+	}
+}
@@ -0,0 +1,174 @@
+package preprocess
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/deboshire/exp/ai"
+	v "github.com/deboshire/exp/math/vector"
+)
+
+type binaryClassifier struct {
+	transformer Transformer
+	classifier  ai.BinaryClassifier
+}
+
+type nominalClassifier struct {
+	transformer Transformer
+	classifier  ai.NominalClassifier
+}
+
+type probabilities struct {
+	transformer Transformer
+	classifier  ai.ProbabilisticClassifier
+}
+
+type probabilisticBinaryClassifier struct {
+	*binaryClassifier
+	probabilities
+}
+
+type probabilisticNominalClassifier struct {
+	*nominalClassifier
+	probabilities
+}
+
+// Applies fitted t to features before classifying them with c. The result
+// is probabilistic if c is, and can be saved if c is an ai.Model.
+func BinaryClassifier(t Transformer, c ai.BinaryClassifier) ai.BinaryClassifier {
+	result := &binaryClassifier{transformer: t, classifier: c}
+	if p, ok := c.(ai.ProbabilisticClassifier); ok {
+		return &probabilisticBinaryClassifier{result, probabilities{t, p}}
+	}
+	return result
+}
+
+// Same as BinaryClassifier for nominal classifiers.
+func NominalClassifier(t Transformer, c ai.NominalClassifier) ai.NominalClassifier {
+	result := &nominalClassifier{transformer: t, classifier: c}
+	if p, ok := c.(ai.ProbabilisticClassifier); ok {
+		return &probabilisticNominalClassifier{result, probabilities{t, p}}
+	}
+	return result
+}
+
+// Fits a new transformer on every training set and trains the classifier
+// on the transformed features.
+func BinaryTrainer(newTransformer func() Transformer, trainer ai.BinaryClassifierTrainer) ai.BinaryClassifierTrainer {
+	return func(features []v.F64, labels []bool) ai.BinaryClassifier {
+		t := newTransformer()
+		return BinaryClassifier(t, trainer(FitTransform(t, features), labels))
+	}
+}
+
+func NominalTrainer(newTransformer func() Transformer, trainer ai.NominalClassifierTrainer) ai.NominalClassifierTrainer {
+	return func(features []v.F64, labels []int, labelsCardinality int) ai.NominalClassifier {
+		t := newTransformer()
+		return NominalClassifier(t, trainer(FitTransform(t, features), labels, labelsCardinality))
+	}
+}
+
+func (c *binaryClassifier) Classify(features v.F64) (bool, float64) {
+	return c.classifier.Classify(c.transformer.Transform(features))
+}
+
+func (c *nominalClassifier) Classify(features v.F64) (int, float64) {
+	return c.classifier.Classify(c.transformer.Transform(features))
+}
+
+func (p probabilities) Probabilities(features v.F64) v.F64 {
+	return p.classifier.Probabilities(p.transformer.Transform(features))
+}
+
+func (p probabilities) LogProbabilities(features v.F64) v.F64 {
+	return p.classifier.LogProbabilities(p.transformer.Transform(features))
+}
+
+const (
+	binaryClassifierType  = "preprocess.binaryClassifier"
+	nominalClassifierType = "preprocess.nominalClassifier"
+)
+
+type classifierState struct {
+	Transformer json.RawMessage
+	Classifier  json.RawMessage
+}
+
+func marshalClassifier(t Transformer, c interface{}, e ai.Encoding) ([]byte, error) {
+	m, ok := c.(ai.Model)
+	if !ok {
+		return nil, fmt.Errorf("%T can not be saved", c)
+	}
+
+	var s classifierState
+	var err error
+	if s.Transformer, err = EncodeTransformer(t, e); err != nil {
+		return nil, err
+	}
+	if s.Classifier, err = ai.EncodeModel(m, e); err != nil {
+		return nil, err
+	}
+	return e.Marshal(s)
+}
+
+func unmarshalClassifier(e ai.Encoding, data []byte) (Transformer, ai.Model, error) {
+	var s classifierState
+	if err := e.Unmarshal(data, &s); err != nil {
+		return nil, nil, err
+	}
+
+	t, err := DecodeTransformer(s.Transformer, e)
+	if err != nil {
+		return nil, nil, err
+	}
+	m, err := ai.DecodeModel(s.Classifier, e)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if m.FeatureDim() != t.OutputDim() {
+		return nil, nil, fmt.Errorf("%s expects %d features, transformer gives %d", m.ModelType(), m.FeatureDim(), t.OutputDim())
+	}
+	return t, m, nil
+}
+
+func (c *binaryClassifier) ModelType() string  { return binaryClassifierType }
+func (c *nominalClassifier) ModelType() string { return nominalClassifierType }
+func (c *binaryClassifier) FeatureDim() int    { return c.transformer.InputDim() }
+func (c *nominalClassifier) FeatureDim() int   { return c.transformer.InputDim() }
+
+func (c *binaryClassifier) MarshalModel(e ai.Encoding) ([]byte, error) {
+	return marshalClassifier(c.transformer, c.classifier, e)
+}
+
+func (c *nominalClassifier) MarshalModel(e ai.Encoding) ([]byte, error) {
+	return marshalClassifier(c.transformer, c.classifier, e)
+}
+
+func loadBinaryClassifier(e ai.Encoding, data []byte) (ai.Model, error) {
+	t, m, err := unmarshalClassifier(e, data)
+	if err != nil {
+		return nil, err
+	}
+	c, ok := m.(ai.BinaryClassifier)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a binary classifier", m.ModelType())
+	}
+	return BinaryClassifier(t, c).(ai.Model), nil
+}
+
+func loadNominalClassifier(e ai.Encoding, data []byte) (ai.Model, error) {
+	t, m, err := unmarshalClassifier(e, data)
+	if err != nil {
+		return nil, err
+	}
+	c, ok := m.(ai.NominalClassifier)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a nominal classifier", m.ModelType())
+	}
+	return NominalClassifier(t, c).(ai.Model), nil
+}
+
+func init() {
+	ai.RegisterModel(binaryClassifierType, loadBinaryClassifier)
+	ai.RegisterModel(nominalClassifierType, loadNominalClassifier)
+}
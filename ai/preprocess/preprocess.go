@@ -0,0 +1,191 @@
+/*
+	Feature preprocessing.
+
+	Transformers are fitted on training data and then applied to every
+	feature vector both at training and at prediction time. They can be
+	chained into a Pipeline and saved together with a classifier.
+*/
+package preprocess
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/deboshire/exp/ai"
+	v "github.com/deboshire/exp/math/vector"
+)
+
+type Transformer interface {
+	// Learns transformation parameters from training data.
+	Fit(features []v.F64)
+	// Returns transformed copy of features.
+	Transform(features v.F64) v.F64
+	// Number of features expected by Transform, known after Fit.
+	InputDim() int
+	// Number of features returned by Transform, known after Fit.
+	OutputDim() int
+	// Name the transformer type was registered with by RegisterTransformer.
+	TransformerType() string
+	MarshalTransformer(e ai.Encoding) ([]byte, error)
+}
+
+// Restores a transformer from parameters produced by its MarshalTransformer.
+type TransformerLoader func(e ai.Encoding, data []byte) (Transformer, error)
+
+var transformerLoaders = make(map[string]TransformerLoader)
+
+// Makes transformers of the given type loadable. Should be called from init.
+func RegisterTransformer(transformerType string, loader TransformerLoader) {
+	if _, ok := transformerLoaders[transformerType]; ok {
+		panic(fmt.Sprintf("Transformer type %s is already registered", transformerType))
+	}
+	transformerLoaders[transformerType] = loader
+}
+
+// Loader for transformers whose exported fields are their parameters.
+func fieldsLoader(newTransformer func() Transformer) TransformerLoader {
+	return func(e ai.Encoding, data []byte) (Transformer, error) {
+		t := newTransformer()
+		if err := e.Unmarshal(data, t); err != nil {
+			return nil, err
+		}
+		return t, nil
+	}
+}
+
+type savedTransformer struct {
+	Type string
+	Data json.RawMessage
+}
+
+func EncodeTransformer(t Transformer, e ai.Encoding) ([]byte, error) {
+	data, err := t.MarshalTransformer(e)
+	if err != nil {
+		return nil, err
+	}
+	return e.Marshal(savedTransformer{Type: t.TransformerType(), Data: data})
+}
+
+func DecodeTransformer(data []byte, e ai.Encoding) (Transformer, error) {
+	var saved savedTransformer
+	if err := e.Unmarshal(data, &saved); err != nil {
+		return nil, err
+	}
+
+	loader, ok := transformerLoaders[saved.Type]
+	if !ok {
+		return nil, fmt.Errorf("Unknown transformer type %q", saved.Type)
+	}
+	return loader(e, saved.Data)
+}
+
+func checkDim(features v.F64, dim int) {
+	if len(features) != dim {
+		panic(fmt.Sprintf("Expected %d features, got %d", dim, len(features)))
+	}
+}
+
+func inputDim(features []v.F64) int {
+	if len(features) == 0 {
+		panic("No training data")
+	}
+	return len(features[0])
+}
+
+// Transforms every feature vector.
+func TransformAll(t Transformer, features []v.F64) []v.F64 {
+	result := make([]v.F64, len(features))
+	for i, f := range features {
+		result[i] = t.Transform(f)
+	}
+	return result
+}
+
+// Fits t and returns transformed training data.
+func FitTransform(t Transformer, features []v.F64) []v.F64 {
+	t.Fit(features)
+	return TransformAll(t, features)
+}
+
+// Applies transformers one after another. Every step is fitted on the
+// output of the previous ones.
+type Pipeline struct {
+	Steps []Transformer
+}
+
+func NewPipeline(steps ...Transformer) *Pipeline {
+	return &Pipeline{Steps: steps}
+}
+
+func (p *Pipeline) Fit(features []v.F64) {
+	for i, step := range p.Steps {
+		step.Fit(features)
+		if i+1 < len(p.Steps) {
+			features = TransformAll(step, features)
+		}
+	}
+}
+
+func (p *Pipeline) Transform(features v.F64) v.F64 {
+	for _, step := range p.Steps {
+		features = step.Transform(features)
+	}
+	return features
+}
+
+func (p *Pipeline) InputDim() int {
+	if len(p.Steps) == 0 {
+		return 0
+	}
+	return p.Steps[0].InputDim()
+}
+
+func (p *Pipeline) OutputDim() int {
+	if len(p.Steps) == 0 {
+		return 0
+	}
+	return p.Steps[len(p.Steps)-1].OutputDim()
+}
+
+const pipelineType = "preprocess.pipeline"
+
+type pipelineState struct {
+	Steps []json.RawMessage
+}
+
+func (p *Pipeline) TransformerType() string { return pipelineType }
+
+func (p *Pipeline) MarshalTransformer(e ai.Encoding) ([]byte, error) {
+	var s pipelineState
+	for _, step := range p.Steps {
+		data, err := EncodeTransformer(step, e)
+		if err != nil {
+			return nil, err
+		}
+		s.Steps = append(s.Steps, data)
+	}
+	return e.Marshal(s)
+}
+
+func loadPipeline(e ai.Encoding, data []byte) (Transformer, error) {
+	var s pipelineState
+	if err := e.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+
+	p := &Pipeline{}
+	for i, data := range s.Steps {
+		step, err := DecodeTransformer(data, e)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 && step.InputDim() != p.Steps[i-1].OutputDim() {
+			return nil, fmt.Errorf("Step %d expects %d features instead of %d", i, step.InputDim(), p.Steps[i-1].OutputDim())
+		}
+		p.Steps = append(p.Steps, step)
+	}
+	return p, nil
+}
+
+func init() {
+	RegisterTransformer(pipelineType, loadPipeline)
+}
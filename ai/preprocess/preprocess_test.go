@@ -0,0 +1,110 @@
+package preprocess
+
+import (
+	"bytes"
+	"github.com/deboshire/exp/ai"
+	"github.com/deboshire/exp/math/opt/sgrad"
+	v "github.com/deboshire/exp/math/vector"
+	"math"
+	"testing"
+)
+
+var nan = math.NaN()
+
+func TestTransformers(t *testing.T) {
+	features := []v.F64{
+		{1, 10, 2, nan},
+		{2, 20, 0, 4},
+		{3, 30, 2, 1},
+		{6, 30, 5, 3},
+	}
+
+	for _, c := range []struct {
+		name     string
+		t        Transformer
+		in, want v.F64
+	}{
+		{"bias", NewBias(), v.F64{5, 6, 7, 8}, v.F64{1, 5, 6, 7, 8}},
+		{"standardizer", NewStandardizer(), v.F64{3, 22.5, 2.25, 0}, v.F64{0, 0, 0, nan}},
+		{"min-max", NewMinMaxScaler(), v.F64{6, 20, 0, 0}, v.F64{1, 0.5, 0, nan}},
+		{"one-hot", NewOneHotEncoder(2, 1), v.F64{7, 30, 2, 8}, v.F64{7, 0, 0, 1, 0, 1, 0, 8}},
+		{"one-hot unseen", NewOneHotEncoder(2), v.F64{7, 30, 1, 8}, v.F64{7, 30, 0, 0, 0, 8}},
+		{"impute mean", NewImputer(ImputeMean, 0), v.F64{nan, 1, 2, nan}, v.F64{3, 1, 2, 8.0 / 3}},
+		{"impute median", NewImputer(ImputeMedian, 0), v.F64{nan, 1, 2, nan}, v.F64{2.5, 1, 2, 3}},
+		{"impute constant", NewImputer(ImputeConstant, -1), v.F64{1, nan, 2, nan}, v.F64{1, -1, 2, -1}},
+	} {
+		c.t.Fit(features)
+		got := c.t.Transform(c.in)
+		if len(got) != len(c.want) || len(got) != c.t.OutputDim() || c.t.InputDim() != 4 {
+			t.Error(c.name, "bad dimensions:", got)
+			continue
+		}
+		for i := range got {
+			if math.Abs(got[i]-c.want[i]) > 1e-12 || math.IsNaN(got[i]) != math.IsNaN(c.want[i]) {
+				t.Error(c.name, "got:", got, "want:", c.want)
+				break
+			}
+		}
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	features := []v.F64{{nan, 0}, {1, 10}, {3, 20}}
+	p := NewPipeline(NewImputer(ImputeMean, 0), NewMinMaxScaler(), NewBias())
+
+	got := FitTransform(p, features)
+	want := []v.F64{{1, 0.5, 0}, {1, 0, 0.5}, {1, 1, 1}}
+	for i := range got {
+		if !got[i].Eq(want[i], 1e-12) {
+			t.Error("got:", got, "want:", want)
+		}
+	}
+
+	data, err := EncodeTransformer(p, ai.JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := DecodeTransformer(data, ai.JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Transform(v.F64{nan, 5}).Eq(v.F64{1, 0.5, 0.25}, 1e-12) {
+		t.Error("Loaded pipeline differs:", loaded.Transform(v.F64{nan, 5}))
+	}
+}
+
+func TestSaveLoadClassifier(t *testing.T) {
+	var features []v.F64
+	var labels v.B
+	for i := 0; i < 40; i++ {
+		features = append(features, v.F64{float64(i * 100)})
+		labels = append(labels, i >= 20)
+	}
+
+	trainer := BinaryTrainer(
+		func() Transformer { return NewPipeline(NewStandardizer(), NewBias()) },
+		ai.NewLogisticRegressionTrainer(0, &sgrad.NumIterationsCrit{NumIterations: 50}, 1e-8))
+	c := trainer(features, labels)
+
+	if a := ai.EvaluateBinaryClassifier(c, features, labels); a < 0.95 {
+		t.Error("Bad accuracy:", a)
+	}
+
+	for _, e := range []ai.Encoding{ai.JSON, ai.Binary} {
+		var b bytes.Buffer
+		if err := ai.SaveModel(&b, c.(ai.Model), e); err != nil {
+			t.Fatal(err)
+		}
+
+		m, err := ai.LoadModel(&b, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		p1 := c.(ai.ProbabilisticClassifier).Probabilities(v.F64{1500})
+		p2 := m.(ai.ProbabilisticClassifier).Probabilities(v.F64{1500})
+		if !p1.Eq(p2, 0) {
+			t.Error("Probabilities differ after loading:", p1, p2)
+		}
+	}
+}
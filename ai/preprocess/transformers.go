@@ -0,0 +1,286 @@
+package preprocess
+
+import (
+	"github.com/deboshire/exp/ai"
+	v "github.com/deboshire/exp/math/vector"
+	"math"
+	"sort"
+)
+
+// Inserts constant 1 as feature 0. Linear models in ai treat feature 0 as
+// unregularized bias term.
+type Bias struct {
+	Dim int
+}
+
+func NewBias() *Bias {
+	return &Bias{}
+}
+
+func (b *Bias) Fit(features []v.F64) {
+	b.Dim = inputDim(features)
+}
+
+func (b *Bias) Transform(features v.F64) v.F64 {
+	checkDim(features, b.Dim)
+	result := v.Zeroes(b.Dim + 1)
+	result[0] = 1
+	copy(result[1:], features)
+	return result
+}
+
+func (b *Bias) InputDim() int  { return b.Dim }
+func (b *Bias) OutputDim() int { return b.Dim + 1 }
+
+// Scales every feature to zero mean and unit variance. Constant features
+// become 0.
+type Standardizer struct {
+	Mean  v.F64
+	Scale v.F64
+}
+
+func NewStandardizer() *Standardizer {
+	return &Standardizer{}
+}
+
+func (s *Standardizer) Fit(features []v.F64) {
+	dim := inputDim(features)
+	s.Mean = v.Zeroes(dim)
+	s.Scale = v.Zeroes(dim)
+
+	for _, f := range features {
+		s.Mean.Add(f)
+	}
+	s.Mean.Mul(1 / float64(len(features)))
+
+	for _, f := range features {
+		for i, x := range f {
+			s.Scale[i] += (x - s.Mean[i]) * (x - s.Mean[i])
+		}
+	}
+	for i, d := range s.Scale {
+		if d == 0 {
+			s.Scale[i] = 1
+		} else {
+			s.Scale[i] = 1 / math.Sqrt(d/float64(len(features)))
+		}
+	}
+}
+
+func (s *Standardizer) Transform(features v.F64) v.F64 {
+	checkDim(features, len(s.Mean))
+	result := features.Copy()
+	for i := range result {
+		result[i] = (result[i] - s.Mean[i]) * s.Scale[i]
+	}
+	return result
+}
+
+func (s *Standardizer) InputDim() int  { return len(s.Mean) }
+func (s *Standardizer) OutputDim() int { return len(s.Mean) }
+
+// Maps every feature into [0, 1] on the training data. Constant features
+// become 0.
+type MinMaxScaler struct {
+	Min   v.F64
+	Scale v.F64
+}
+
+func NewMinMaxScaler() *MinMaxScaler {
+	return &MinMaxScaler{}
+}
+
+func (s *MinMaxScaler) Fit(features []v.F64) {
+	dim := inputDim(features)
+	s.Min = features[0].Copy()
+	max := features[0].Copy()
+
+	for _, f := range features {
+		for i, x := range f {
+			s.Min[i] = math.Min(s.Min[i], x)
+			max[i] = math.Max(max[i], x)
+		}
+	}
+
+	s.Scale = v.Zeroes(dim)
+	for i := range s.Scale {
+		if max[i] > s.Min[i] {
+			s.Scale[i] = 1 / (max[i] - s.Min[i])
+		}
+	}
+}
+
+func (s *MinMaxScaler) Transform(features v.F64) v.F64 {
+	checkDim(features, len(s.Min))
+	result := features.Copy()
+	for i := range result {
+		result[i] = (result[i] - s.Min[i]) * s.Scale[i]
+	}
+	return result
+}
+
+func (s *MinMaxScaler) InputDim() int  { return len(s.Min) }
+func (s *MinMaxScaler) OutputDim() int { return len(s.Min) }
+
+// Replaces every nominal column with indicators of its values seen in
+// training data. Unseen values have all indicators 0.
+type OneHotEncoder struct {
+	// Nominal columns, in increasing order.
+	Columns []int
+	Dim     int
+	// Sorted distinct values of every nominal column.
+	Values [][]float64
+}
+
+func NewOneHotEncoder(columns ...int) *OneHotEncoder {
+	columns = append([]int(nil), columns...)
+	sort.Ints(columns)
+	return &OneHotEncoder{Columns: columns}
+}
+
+func (o *OneHotEncoder) Fit(features []v.F64) {
+	o.Dim = inputDim(features)
+	o.Values = make([][]float64, len(o.Columns))
+
+	for i, column := range o.Columns {
+		seen := make(map[float64]bool)
+		for _, f := range features {
+			if x := f[column]; !seen[x] {
+				seen[x] = true
+				o.Values[i] = append(o.Values[i], x)
+			}
+		}
+		sort.Float64s(o.Values[i])
+	}
+}
+
+func (o *OneHotEncoder) Transform(features v.F64) v.F64 {
+	checkDim(features, o.Dim)
+	result := make(v.F64, 0, o.OutputDim())
+
+	next := 0
+	for j, x := range features {
+		if next < len(o.Columns) && o.Columns[next] == j {
+			values := o.Values[next]
+			k := sort.SearchFloat64s(values, x)
+			for i := range values {
+				if i == k && values[k] == x {
+					result = append(result, 1)
+				} else {
+					result = append(result, 0)
+				}
+			}
+			next++
+		} else {
+			result = append(result, x)
+		}
+	}
+	return result
+}
+
+func (o *OneHotEncoder) InputDim() int { return o.Dim }
+
+func (o *OneHotEncoder) OutputDim() int {
+	dim := o.Dim - len(o.Columns)
+	for _, values := range o.Values {
+		dim += len(values)
+	}
+	return dim
+}
+
+type ImputeStrategy int
+
+const (
+	ImputeMean ImputeStrategy = iota
+	ImputeMedian
+	// Replaces missing values with Imputer.Value.
+	ImputeConstant
+)
+
+// Replaces missing values, represented by NaN, with a per feature value
+// learned from training data. Features missing in all training examples
+// are replaced with 0.
+type Imputer struct {
+	Strategy ImputeStrategy
+	Value    float64
+	Fill     v.F64
+}
+
+func NewImputer(strategy ImputeStrategy, value float64) *Imputer {
+	return &Imputer{Strategy: strategy, Value: value}
+}
+
+func (m *Imputer) Fit(features []v.F64) {
+	dim := inputDim(features)
+	m.Fill = v.Zeroes(dim)
+
+	for i := range m.Fill {
+		if m.Strategy == ImputeConstant {
+			m.Fill[i] = m.Value
+			continue
+		}
+
+		var present []float64
+		for _, f := range features {
+			if !math.IsNaN(f[i]) {
+				present = append(present, f[i])
+			}
+		}
+		if len(present) == 0 {
+			continue
+		}
+
+		if m.Strategy == ImputeMedian {
+			sort.Float64s(present)
+			n := len(present)
+			m.Fill[i] = (present[(n-1)/2] + present[n/2]) / 2
+		} else {
+			for _, x := range present {
+				m.Fill[i] += x
+			}
+			m.Fill[i] /= float64(len(present))
+		}
+	}
+}
+
+func (m *Imputer) Transform(features v.F64) v.F64 {
+	checkDim(features, len(m.Fill))
+	result := features.Copy()
+	for i, x := range result {
+		if math.IsNaN(x) {
+			result[i] = m.Fill[i]
+		}
+	}
+	return result
+}
+
+func (m *Imputer) InputDim() int  { return len(m.Fill) }
+func (m *Imputer) OutputDim() int { return len(m.Fill) }
+
+const (
+	biasType          = "preprocess.bias"
+	standardizerType  = "preprocess.standardizer"
+	minMaxScalerType  = "preprocess.minMaxScaler"
+	oneHotEncoderType = "preprocess.oneHotEncoder"
+	imputerType       = "preprocess.imputer"
+)
+
+func (b *Bias) TransformerType() string          { return biasType }
+func (s *Standardizer) TransformerType() string  { return standardizerType }
+func (s *MinMaxScaler) TransformerType() string  { return minMaxScalerType }
+func (o *OneHotEncoder) TransformerType() string { return oneHotEncoderType }
+func (m *Imputer) TransformerType() string       { return imputerType }
+
+func (b *Bias) MarshalTransformer(e ai.Encoding) ([]byte, error)          { return e.Marshal(b) }
+func (s *Standardizer) MarshalTransformer(e ai.Encoding) ([]byte, error)  { return e.Marshal(s) }
+func (s *MinMaxScaler) MarshalTransformer(e ai.Encoding) ([]byte, error)  { return e.Marshal(s) }
+func (o *OneHotEncoder) MarshalTransformer(e ai.Encoding) ([]byte, error) { return e.Marshal(o) }
+func (m *Imputer) MarshalTransformer(e ai.Encoding) ([]byte, error)       { return e.Marshal(m) }
+
+func init() {
+	RegisterTransformer(biasType, fieldsLoader(func() Transformer { return &Bias{} }))
+	RegisterTransformer(standardizerType, fieldsLoader(func() Transformer { return &Standardizer{} }))
+	RegisterTransformer(minMaxScalerType, fieldsLoader(func() Transformer { return &MinMaxScaler{} }))
+	RegisterTransformer(oneHotEncoderType, fieldsLoader(func() Transformer { return &OneHotEncoder{} }))
+	RegisterTransformer(imputerType, fieldsLoader(func() Transformer { return &Imputer{} }))
+}
@@ -0,0 +1,163 @@
+package ai
+
+import (
+	"fmt"
+	"github.com/deboshire/exp/math/opt/sgrad"
+	v "github.com/deboshire/exp/math/vector"
+	"math/rand"
+)
+
+// Multinomial logistic regression.
+type softmaxRegressionClassifier struct {
+	cost              float64
+	labelsCardinality int
+	// labelsCardinality rows of len(features) weights each.
+	theta v.F64
+}
+
+func TrainSoftmaxRegressionClassifier(
+	features []v.F64,
+	labels []int,
+	labelsCardinality int,
+	lambda float64,
+	termCrit sgrad.TermCrit,
+	eps float64) NominalClassifier {
+	return TrainSoftmaxRegressionClassifierWithRand(features, labels, labelsCardinality, lambda, termCrit, eps, nil)
+}
+
+// Same as TrainSoftmaxRegressionClassifier, but draws the order of training
+// examples from r instead of the global source.
+func TrainSoftmaxRegressionClassifierWithRand(
+	features []v.F64,
+	labels []int,
+	labelsCardinality int,
+	lambda float64,
+	termCrit sgrad.TermCrit,
+	eps float64,
+	r *rand.Rand) NominalClassifier {
+	y, x := sgrad.MinimizeWithOptions(
+		softmaxRegressionCostFunction(features, labels, labelsCardinality, lambda),
+		v.Zeroes(labelsCardinality*len(features[0])),
+		eps,
+		termCrit,
+		nil,
+		sgrad.Options{Rand: r})
+
+	return &softmaxRegressionClassifier{cost: y, labelsCardinality: labelsCardinality, theta: x}
+}
+
+func NewSoftmaxRegressionTrainer(
+	lambda float64,
+	termCrit sgrad.TermCrit,
+	eps float64) NominalClassifierTrainer {
+	return func(features []v.F64, labels []int, labelsCardinality int) NominalClassifier {
+		return TrainSoftmaxRegressionClassifier(features, labels, labelsCardinality, lambda, termCrit, eps)
+	}
+}
+
+// Cross-entropy of the softmax model. As in logisticRegressionCostFunction
+// feature 0 is assumed to be the bias term and is not regularized.
+func softmaxRegressionCostFunction(features []v.F64, labels []int, labelsCardinality int, lambda float64) sgrad.ObjectiveFunc {
+	dim := len(features[0])
+
+	f := func(idx int, x v.F64, gradient v.F64) (value float64) {
+		feature := features[idx]
+		label := labels[idx]
+
+		p := v.Zeroes(labelsCardinality)
+		for k := range p {
+			p[k] = x[k*dim : (k+1)*dim].DotProduct(feature)
+		}
+		z := p[label]
+		value = p.Softmax() - z
+
+		for k := range p {
+			g := gradient[k*dim : (k+1)*dim]
+			feature.CopyTo(g)
+			if k == label {
+				g.Mul(p[k] - 1.0)
+			} else {
+				g.Mul(p[k])
+			}
+		}
+
+		if lambda != 0.0 {
+			// apply regularization.
+			for k := 0; k < labelsCardinality; k++ {
+				for i := k*dim + 1; i < (k+1)*dim; i++ {
+					value += 0.5 * lambda * x[i] * x[i]
+					gradient[i] += lambda * x[i]
+				}
+			}
+		}
+
+		return
+	}
+
+	return sgrad.ObjectiveFunc{Terms: len(features), F: f}
+}
+
+func (c *softmaxRegressionClassifier) scores(features v.F64) v.F64 {
+	dim := len(c.theta) / c.labelsCardinality
+	z := v.Zeroes(c.labelsCardinality)
+	for k := range z {
+		z[k] = c.theta[k*dim : (k+1)*dim].DotProduct(features)
+	}
+	return z
+}
+
+func (c *softmaxRegressionClassifier) Probabilities(features v.F64) v.F64 {
+	p := c.scores(features)
+	p.Softmax()
+	return p
+}
+
+func (c *softmaxRegressionClassifier) LogProbabilities(features v.F64) v.F64 {
+	z := c.scores(features)
+	lse := z.Copy().Softmax()
+	for k := range z {
+		z[k] -= lse
+	}
+	return z
+}
+
+// Confidence is the estimated probability of the returned class.
+func (c *softmaxRegressionClassifier) Classify(features v.F64) (result int, confidence float64) {
+	p := c.Probabilities(features)
+	for k := range p {
+		if p[k] > p[result] {
+			result = k
+		}
+	}
+	return result, p[result]
+}
+
+const softmaxRegressionModelType = "ai.softmaxRegression"
+
+type softmaxRegressionState struct {
+	Cost              float64
+	LabelsCardinality int
+	Theta             v.F64
+}
+
+func (c *softmaxRegressionClassifier) ModelType() string { return softmaxRegressionModelType }
+func (c *softmaxRegressionClassifier) FeatureDim() int   { return len(c.theta) / c.labelsCardinality }
+
+func (c *softmaxRegressionClassifier) MarshalModel(e Encoding) ([]byte, error) {
+	return e.Marshal(softmaxRegressionState{Cost: c.cost, LabelsCardinality: c.labelsCardinality, Theta: c.theta})
+}
+
+func loadSoftmaxRegression(e Encoding, data []byte) (Model, error) {
+	var s softmaxRegressionState
+	if err := e.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if s.LabelsCardinality <= 0 || len(s.Theta) == 0 || len(s.Theta)%s.LabelsCardinality != 0 {
+		return nil, fmt.Errorf("Bad theta size %d for %d classes", len(s.Theta), s.LabelsCardinality)
+	}
+	return &softmaxRegressionClassifier{cost: s.Cost, labelsCardinality: s.LabelsCardinality, theta: s.Theta}, nil
+}
+
+func init() {
+	RegisterModel(softmaxRegressionModelType, loadSoftmaxRegression)
+}
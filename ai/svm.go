@@ -0,0 +1,317 @@
+package ai
+
+import (
+	"fmt"
+	"github.com/deboshire/exp/math/opt/sgrad"
+	v "github.com/deboshire/exp/math/vector"
+	"math"
+	"math/rand"
+)
+
+type SVMLoss int
+
+const (
+	// max(0, 1 - y*margin)
+	HingeLoss SVMLoss = iota
+	// max(0, 1 - y*margin)^2, differentiable and more sensitive to outliers.
+	SquaredHingeLoss
+)
+
+func (l SVMLoss) String() string {
+	switch l {
+	case HingeLoss:
+		return "hinge"
+	case SquaredHingeLoss:
+		return "squared hinge"
+	}
+	return fmt.Sprintf("SVMLoss(%d)", int(l))
+}
+
+type LinearSVMOptions struct {
+	// Regularization parameter, must be positive.
+	Lambda float64
+	Loss   SVMLoss
+	// Fits a sigmoid mapping margins to probabilities on the training set
+	// (Platt scaling). Without it confidence is the margin capped at 1 and
+	// the classifier is not probabilistic.
+	PlattScaling bool
+}
+
+type linearSVMClassifier struct {
+	theta v.F64
+}
+
+// Margins mapped to P(true) = 1/(1+exp(a*margin+b)).
+type plattSVMClassifier struct {
+	*linearSVMClassifier
+	a, b float64
+}
+
+// Trains linear SVM with Pegasos: stochastic subgradient descent on the
+// regularized loss with step 1/(lambda*t) at step t, t starting from
+// lambda^(-3/4) rather than 1 so that the unregularized bias does not
+// overshoot, and the weights projected onto the ball containing the optimum.
+// features[0] is treated as the bias term and is not regularized, so it
+// should be constant 1 (see preprocess.Bias).
+func TrainLinearSVMClassifier(
+	features []v.F64,
+	labels v.B,
+	o LinearSVMOptions,
+	termCrit sgrad.TermCrit,
+	eps float64) BinaryClassifier {
+	return TrainLinearSVMClassifierWithRand(features, labels, o, termCrit, eps, nil)
+}
+
+// Same as TrainLinearSVMClassifier, but draws the order of training
+// examples from r instead of the global source.
+func TrainLinearSVMClassifierWithRand(
+	features []v.F64,
+	labels v.B,
+	o LinearSVMOptions,
+	termCrit sgrad.TermCrit,
+	eps float64,
+	r *rand.Rand) BinaryClassifier {
+
+	if o.Lambda <= 0 {
+		panic(fmt.Sprintf("Pegasos needs positive lambda, got %g", o.Lambda))
+	}
+
+	// See Bottou, "Stochastic Gradient Descent Tricks".
+	t0 := math.Pow(o.Lambda, -0.75)
+	_, theta := sgrad.MinimizeWithOptions(
+		svmCostFunction(features, labels, o),
+		v.Zeroes(len(features[0])),
+		eps,
+		termCrit,
+		nil,
+		sgrad.Options{
+			Rand:    r,
+			Rate:    func(pass, step int) float64 { return 1 / (o.Lambda * (float64(step) + t0)) },
+			Project: svmProjection(o.Lambda),
+		})
+
+	c := &linearSVMClassifier{theta: theta}
+	if !o.PlattScaling {
+		return c
+	}
+
+	margins := v.Zeroes(len(features))
+	for i, f := range features {
+		margins[i] = c.margin(f)
+	}
+	a, b := fitPlatt(margins, labels)
+	return &plattSVMClassifier{c, a, b}
+}
+
+func NewLinearSVMTrainer(
+	o LinearSVMOptions,
+	termCrit sgrad.TermCrit,
+	eps float64) BinaryClassifierTrainer {
+	return func(features []v.F64, labels []bool) BinaryClassifier {
+		return TrainLinearSVMClassifier(features, labels, o, termCrit, eps)
+	}
+}
+
+// Trainers run concurrently must not share termCrit state, see
+// NewLogisticRegressionRandTrainer.
+func NewLinearSVMRandTrainer(
+	o LinearSVMOptions,
+	termCrit sgrad.TermCrit,
+	eps float64) RandBinaryClassifierTrainer {
+	return func(features []v.F64, labels []bool, r *rand.Rand) BinaryClassifier {
+		return TrainLinearSVMClassifierWithRand(features, labels, o, termCrit, eps, r)
+	}
+}
+
+// Term i is lambda/2*|theta|^2 + loss(y_i * theta.x_i), x[0] not regularized.
+func svmCostFunction(features []v.F64, labels v.B, o LinearSVMOptions) sgrad.ObjectiveFunc {
+	f := func(idx int, x v.F64, gradient v.F64) (value float64) {
+		feature := features[idx]
+		y := -1.0
+		if labels[idx] {
+			y = 1
+		}
+
+		slack := 1 - y*x.DotProduct(feature)
+		feature.CopyTo(gradient)
+		switch {
+		case slack <= 0:
+			gradient.Mul(0)
+		case o.Loss == HingeLoss:
+			value = slack
+			gradient.Mul(-y)
+		case o.Loss == SquaredHingeLoss:
+			value = slack * slack
+			gradient.Mul(-2 * y * slack)
+		default:
+			panic(fmt.Sprintf("Unknown SVM loss: %d", o.Loss))
+		}
+
+		for i := 1; i < len(x); i++ {
+			value += 0.5 * o.Lambda * x[i] * x[i]
+			gradient[i] += o.Lambda * x[i]
+		}
+		return
+	}
+
+	return sgrad.ObjectiveFunc{Terms: len(features), F: f}
+}
+
+// Pegasos projection: the objective at the optimum is at most the loss of
+// theta = 0, which is 1, so the regularized weights have norm at most
+// sqrt(2/lambda).
+func svmProjection(lambda float64) func(x v.F64) {
+	radius2 := 2 / lambda
+	return func(x v.F64) {
+		norm2 := x[1:].DotProduct(x[1:])
+		if norm2 > radius2 {
+			x[1:].Mul(math.Sqrt(radius2 / norm2))
+		}
+	}
+}
+
+// Platt scaling: maximum likelihood fit of P(true) = 1/(1+exp(a*margin+b))
+// with regularized targets, by Newton's method with backtracking as in
+// Lin, Lin and Weng, "A note on Platt's probabilistic outputs for support
+// vector machines".
+func fitPlatt(margins v.F64, labels v.B) (a, b float64) {
+	var prior0, prior1 float64
+	for _, l := range labels {
+		if l {
+			prior1++
+		} else {
+			prior0++
+		}
+	}
+
+	targets := v.Zeroes(len(labels))
+	for i, l := range labels {
+		if l {
+			targets[i] = (prior1 + 1) / (prior1 + 2)
+		} else {
+			targets[i] = 1 / (prior0 + 2)
+		}
+	}
+
+	// Negative log-likelihood, without overflow.
+	nll := func(a, b float64) (value float64) {
+		for i, m := range margins {
+			z := a*m + b
+			if z >= 0 {
+				value += targets[i]*z + math.Log1p(math.Exp(-z))
+			} else {
+				value += (targets[i]-1)*z + math.Log1p(math.Exp(z))
+			}
+		}
+		return
+	}
+
+	b = math.Log((prior0 + 1) / (prior1 + 1))
+	value := nll(a, b)
+
+	for iteration := 0; iteration < 100; iteration++ {
+		// Gradient and Hessian, the latter slightly regularized.
+		h11, h22, h21 := 1e-12, 1e-12, 0.0
+		g1, g2 := 0.0, 0.0
+		for i, m := range margins {
+			p := sigmoid(-(a*m + b))
+			d2 := p * (1 - p)
+			h11 += m * m * d2
+			h22 += d2
+			h21 += m * d2
+			d1 := targets[i] - p
+			g1 += m * d1
+			g2 += d1
+		}
+		if math.Abs(g1) < 1e-5 && math.Abs(g2) < 1e-5 {
+			break
+		}
+
+		det := h11*h22 - h21*h21
+		da := -(h22*g1 - h21*g2) / det
+		db := -(-h21*g1 + h11*g2) / det
+		descent := g1*da + g2*db
+
+		step := 1.0
+		for ; step >= 1e-10; step /= 2 {
+			newA, newB := a+step*da, b+step*db
+			if newValue := nll(newA, newB); newValue < value+1e-4*step*descent {
+				a, b, value = newA, newB, newValue
+				break
+			}
+		}
+		if step < 1e-10 {
+			break
+		}
+	}
+	return a, b
+}
+
+// Signed distance-like score, positive for true.
+func (c *linearSVMClassifier) margin(features v.F64) float64 {
+	return c.theta.DotProduct(features)
+}
+
+// Confidence is |margin| capped at 1: examples outside of the margin are
+// certain.
+func (c *linearSVMClassifier) Classify(features v.F64) (bool, float64) {
+	m := c.margin(features)
+	return m >= 0, math.Min(1, math.Abs(m))
+}
+
+func (c *plattSVMClassifier) Probabilities(features v.F64) v.F64 {
+	p := sigmoid(-(c.a*c.margin(features) + c.b))
+	return v.F64{1 - p, p}
+}
+
+func (c *plattSVMClassifier) LogProbabilities(features v.F64) v.F64 {
+	z := -(c.a*c.margin(features) + c.b)
+	return v.F64{logSigmoid(-z), logSigmoid(z)}
+}
+
+// Confidence follows logistic regression: |0.5-P(true)|*2. The calibrated
+// decision threshold may differ slightly from margin 0.
+func (c *plattSVMClassifier) Classify(features v.F64) (bool, float64) {
+	p := c.Probabilities(features)[1]
+	return p >= 0.5, math.Abs(0.5-p) * 2
+}
+
+const linearSVMModelType = "ai.linearSVM"
+
+type linearSVMState struct {
+	Theta v.F64
+	// Platt scaling parameters, if fitted.
+	Platt bool
+	A, B  float64
+}
+
+func (c *linearSVMClassifier) ModelType() string { return linearSVMModelType }
+func (c *linearSVMClassifier) FeatureDim() int   { return len(c.theta) }
+
+func (c *linearSVMClassifier) MarshalModel(e Encoding) ([]byte, error) {
+	return e.Marshal(linearSVMState{Theta: c.theta})
+}
+
+func (c *plattSVMClassifier) MarshalModel(e Encoding) ([]byte, error) {
+	return e.Marshal(linearSVMState{Theta: c.theta, Platt: true, A: c.a, B: c.b})
+}
+
+func loadLinearSVM(e Encoding, data []byte) (Model, error) {
+	var s linearSVMState
+	if err := e.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if len(s.Theta) == 0 {
+		return nil, errEmptyModel
+	}
+
+	c := &linearSVMClassifier{theta: s.Theta}
+	if s.Platt {
+		return &plattSVMClassifier{c, s.A, s.B}, nil
+	}
+	return c, nil
+}
+
+func init() {
+	RegisterModel(linearSVMModelType, loadLinearSVM)
+}
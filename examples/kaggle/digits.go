@@ -6,14 +6,27 @@ import (
 	"flag"
 	"fmt"
 	"github.com/deboshire/exp/ai"
+	"github.com/deboshire/exp/ai/mlp"
+	"github.com/deboshire/exp/ai/pca"
+	"github.com/deboshire/exp/ai/preprocess"
 	"github.com/deboshire/exp/math/opt/sgrad"
 	v "github.com/deboshire/exp/math/vector"
+	"math/rand"
 	"os"
 	"runtime/pprof"
 	"strconv"
+	"strings"
 )
 
 var trainCsvPath = flag.String("train-csv", "", "Path to train.csv file from kaggle")
+var concurrency = flag.Int("concurrency", 0, "Number of classifiers trained at once, GOMAXPROCS if 0")
+var seed = flag.Int64("seed", 1, "Random seed")
+var reportCsvPath = flag.String("report-csv", "", "Path to write per-class training set report to")
+var modelPath = flag.String("model", "", "Path to a saved model. Trained and saved there if missing")
+var modelBinary = flag.Bool("model-binary", false, "Save the model in binary instead of JSON")
+var hidden = flag.String("hidden", "", "Comma separated hidden layer sizes of a neural network to use instead of logistic regression")
+var pcaComponents = flag.Int("pca", 0, "Reduce pixels to this many principal components before training")
+var knn = flag.Int("knn", 0, "Use k-nearest-neighbour classifier with this k instead of logistic regression")
 
 func parseVector(strs []string) (res v.F64, err error) {
 	res = v.Zeroes(len(strs))
@@ -28,6 +41,109 @@ func parseVector(strs []string) (res v.F64, err error) {
 	return
 }
 
+func parseSizes(str string) (sizes []int) {
+	for _, s := range strings.Split(str, ",") {
+		size, err := strconv.Atoi(s)
+		if err != nil {
+			panic(err)
+		}
+		sizes = append(sizes, size)
+	}
+	return
+}
+
+// Pixels scaled into [0, 1], then optionally reduced by PCA.
+func scaling() []preprocess.Transformer {
+	steps := []preprocess.Transformer{preprocess.NewMinMaxScaler()}
+	if *pcaComponents > 0 {
+		steps = append(steps, pca.New(pca.Options{Components: *pcaComponents}))
+	}
+	return steps
+}
+
+func train(pixels []v.F64, labels []int) ai.NominalClassifier {
+	if *hidden != "" {
+		scaler := preprocess.NewPipeline(scaling()...)
+		features := preprocess.FitTransform(scaler, pixels)
+		classifier := mlp.Train(
+			features,
+			labels,
+			10,
+			mlp.Options{Hidden: parseSizes(*hidden), Activation: mlp.ReLU, Lambda: 1e-4},
+			&sgrad.NumIterationsCrit{NumIterations: 10},
+			1e-8,
+			rand.New(rand.NewSource(*seed)))
+		return preprocess.NominalClassifier(scaler, classifier)
+	}
+
+	pipeline := preprocess.NewPipeline(append(scaling(), preprocess.NewBias())...)
+	features := preprocess.FitTransform(pipeline, pixels)
+
+	if *knn > 0 {
+		classifier := ai.TrainKNNClassifier(features, labels, 10, *knn, true, ai.AutoIndex)
+		return preprocess.NominalClassifier(pipeline, classifier)
+	}
+
+	binClassifierTrainer := func(features []v.F64, labels []bool, r *rand.Rand) ai.BinaryClassifier {
+		fmt.Println("Training binary classifier")
+		classifier := ai.TrainLogisticRegressionClassifierWithRand(
+			features,
+			labels,
+			0,
+			&sgrad.NumIterationsCrit{NumIterations: 10},
+			1e-8,
+			r)
+		return classifier
+	}
+
+	classifier, err := ai.TrainNominalClassifierFromBinaryParallel(
+		features,
+		labels,
+		10,
+		binClassifierTrainer,
+		*concurrency,
+		rand.New(rand.NewSource(*seed)))
+	if err != nil {
+		panic(err)
+	}
+	return preprocess.NominalClassifier(pipeline, classifier)
+}
+
+// Returns nil if there is no model at path.
+func loadModel(path string, featureDim int) ai.NominalClassifier {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	fmt.Println("Loading model from", path)
+	m, err := ai.LoadModel(bufio.NewReader(f), featureDim)
+	if err != nil {
+		panic(err)
+	}
+	return m.(ai.NominalClassifier)
+}
+
+func saveModel(path string, m ai.Model) {
+	f, err := os.Create(path)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	var e ai.Encoding = ai.JSON
+	if *modelBinary {
+		e = ai.Binary
+	}
+	if err := ai.SaveModel(f, m, e); err != nil {
+		panic(err)
+	}
+}
+
 func main() {
 	flag.Parse()
 
@@ -57,7 +173,6 @@ func main() {
 	labels := make([]int, len(allData))
 	pixels := make([]v.F64, len(allData))
 
-	// TODO(mike): add bias term
 	for i, row := range allData {
 		parsedLabel, err := strconv.ParseInt(row[0], 10, 32)
 		if err != nil {
@@ -67,17 +182,29 @@ func main() {
 		pixels[i], err = parseVector(row[1:])
 	}
 
-	binClassifierTrainer := func(features []v.F64, labels []bool) ai.BinaryClassifier {
-		fmt.Println("Training binary classifier")
-		classifier := ai.TrainLogisticRegressionClassifier(
-			features,
-			labels,
-			0,
-			&sgrad.NumIterationsCrit{NumIterations: 10},
-			1e-8)
-		return classifier
+	var classifier ai.NominalClassifier
+	if *modelPath != "" {
+		classifier = loadModel(*modelPath, len(pixels[0]))
+	}
+
+	if classifier == nil {
+		classifier = train(pixels, labels)
+		if *modelPath != "" {
+			saveModel(*modelPath, classifier.(ai.Model))
+		}
 	}
 
-	classifier := ai.TrainNominalClassifierFromBinary(pixels, labels, 10, binClassifierTrainer)
-	fmt.Println(classifier)
+	report := ai.EvaluateNominalClassifier(classifier, pixels, labels, 10)
+	fmt.Print("Training set:\n", report)
+
+	if *reportCsvPath != "" {
+		f, err := os.Create(*reportCsvPath)
+		if err != nil {
+			panic(err)
+		}
+		defer f.Close()
+		if err := report.WriteCSV(f); err != nil {
+			panic(err)
+		}
+	}
 }
@@ -3,6 +3,8 @@ package pgm7
 import (
 	"fmt"
 	"github.com/deboshire/exp/ai"
+	"github.com/deboshire/exp/ai/pca"
+	"github.com/deboshire/exp/ai/preprocess"
 	"github.com/deboshire/exp/io/mat"
 	"github.com/deboshire/exp/math/opt/gssearch"
 	"github.com/deboshire/exp/math/opt/sgrad"
@@ -22,6 +24,16 @@ func readBenchmarkData() (benchmarkFeatures []v.F64, benchmarkLabels v.B) {
 	return
 }
 
+func nominalLabels(labels v.B) []int {
+	result := make([]int, len(labels))
+	for i, l := range labels {
+		if l {
+			result[i] = 1
+		}
+	}
+	return result
+}
+
 func ExamplePGM7_LogisticRegression_HoldoutTesting() {
 	rand.Seed(98765)
 	trainFeatures, trainLabels := readTrainData()
@@ -53,6 +65,65 @@ func ExamplePGM7_LogisticRegression_HoldoutTesting() {
 	// Holdout testing: 1
 }
 
+func ExamplePGM7_LogisticRegression_CrossValidation() {
+	r := rand.New(rand.NewSource(98765))
+	trainFeatures, trainLabels := readTrainData()
+	trainer := ai.NewLogisticRegressionRandTrainer(0,
+		&sgrad.NumIterationsCrit{NumIterations: 10},
+		1e-8).WithRand(r)
+
+	for _, folds := range []ai.Folds{
+		ai.KFolds(len(trainFeatures), 5, r),
+		ai.StratifiedBinaryKFolds(trainLabels, 5, r),
+		ai.LeaveOneOutFolds(len(trainFeatures)),
+	} {
+		result := ai.CrossValidateBinaryClassifier(trainFeatures, trainLabels, folds, trainer)
+		fmt.Printf("---\nfolds: %d\nmean: %.3f\nstddev: %.3f\n", len(folds), result.Mean, result.StdDev)
+	}
+
+	// Output:
+	// ---
+	// folds: 5
+	// mean: 0.875
+	// stddev: 0.032
+	// ---
+	// folds: 5
+	// mean: 0.910
+	// stddev: 0.046
+	// ---
+	// folds: 200
+	// mean: 0.895
+	// stddev: 0.307
+}
+
+func ExamplePGM7_LogisticRegression_Report() {
+	r := rand.New(rand.NewSource(98765))
+	trainFeatures, trainLabels := readTrainData()
+	benchmarkFeatures, benchmarkLabels := readBenchmarkData()
+
+	classifier := ai.TrainLogisticRegressionClassifierWithRand(
+		trainFeatures,
+		trainLabels,
+		0.1,
+		&sgrad.NumIterationsCrit{NumIterations: 10},
+		1e-8,
+		r)
+	fmt.Print(ai.EvaluateBinaryClassifierReport(classifier, benchmarkFeatures, benchmarkLabels))
+
+	// Output:
+	//           predicted+  predicted-
+	// actual+          82          15
+	// actual-           3         100
+	// accuracy: 0.9100
+	// precision: 0.9647
+	// recall: 0.8454
+	// f1: 0.9011
+	// specificity: 0.9709
+	// log-loss: 0.2779
+	// auc: 0.9787
+	// average precision: 0.9740
+}
+
 func ExamplePGM7_LogisticRegression_Iterations() {
 	rand.Seed(98765)
 	trainFeatures, trainLabels := readTrainData()
@@ -198,3 +269,265 @@ func ExamplePGM7_LogisticRegression_OptimizeLambda() {
 	// train set:  0.895
 	// benchmark set:  0.9
 }
+
+func ExamplePGM7_NaiveBayes() {
+	trainFeatures, trainLabels := readTrainData()
+	benchmarkFeatures, benchmarkLabels := readBenchmarkData()
+
+	for _, kind := range []ai.NaiveBayesKind{ai.GaussianNaiveBayes, ai.BernoulliNaiveBayes, ai.MultinomialNaiveBayes} {
+		fmt.Println("---\nkind: ", kind)
+		classifier := ai.NewNaiveBayesBinaryTrainer(kind, 1)(trainFeatures, trainLabels)
+		fmt.Println("train set: ", ai.EvaluateBinaryClassifier(classifier, trainFeatures, trainLabels))
+		fmt.Println("benchmark set: ", ai.EvaluateBinaryClassifier(classifier, benchmarkFeatures, benchmarkLabels))
+	}
+
+	// Output:
+	// ---
+	// kind:  gaussian
+	// train set:  0.885
+	// benchmark set:  0.895
+	// ---
+	// kind:  bernoulli
+	// train set:  0.9
+	// benchmark set:  0.935
+	// ---
+	// kind:  multinomial
+	// train set:  0.895
+	// benchmark set:  0.925
+}
+
+func ExamplePGM7_DecisionTree() {
+	trainFeatures, trainLabels := readTrainData()
+	benchmarkFeatures, benchmarkLabels := readBenchmarkData()
+
+	for _, o := range []ai.DecisionTreeOptions{
+		{},
+		{MaxDepth: 3},
+		{MinSamplesLeaf: 10},
+		{Criterion: ai.EntropyCriterion, MinSamplesLeaf: 10},
+		{PruningAlpha: 0.01},
+	} {
+		fmt.Printf("---\noptions: %+v\n", o)
+		classifier := ai.NewDecisionTreeBinaryTrainer(o)(trainFeatures, trainLabels)
+		fmt.Println("train set: ", ai.EvaluateBinaryClassifier(classifier, trainFeatures, trainLabels))
+		fmt.Println("benchmark set: ", ai.EvaluateBinaryClassifier(classifier, benchmarkFeatures, benchmarkLabels))
+	}
+
+	fmt.Print("---\n", ai.TrainDecisionTree(trainFeatures, nominalLabels(trainLabels), 2, ai.DecisionTreeOptions{MaxDepth: 2}))
+
+	// Output:
+	// ---
+	// options: {Criterion:gini MaxDepth:0 MinSamplesLeaf:0 PruningAlpha:0}
+	// train set:  1
+	// benchmark set:  0.915
+	// ---
+	// options: {Criterion:gini MaxDepth:3 MinSamplesLeaf:0 PruningAlpha:0}
+	// train set:  0.88
+	// benchmark set:  0.845
+	// ---
+	// options: {Criterion:gini MaxDepth:0 MinSamplesLeaf:10 PruningAlpha:0}
+	// train set:  0.885
+	// benchmark set:  0.855
+	// ---
+	// options: {Criterion:entropy MaxDepth:0 MinSamplesLeaf:10 PruningAlpha:0}
+	// train set:  0.885
+	// benchmark set:  0.855
+	// ---
+	// options: {Criterion:gini MaxDepth:0 MinSamplesLeaf:0 PruningAlpha:0.01}
+	// train set:  0.915
+	// benchmark set:  0.85
+	// ---
+	// x25 <= 0.5
+	//   x117 <= 0.5
+	//     class 1 (0.730 of 115)
+	//   x117 > 0.5
+	//     class 0 (0.839 of 31)
+	// x25 > 0.5
+	//   x123 <= 0.5
+	//     class 0 (0.962 of 52)
+	//   x123 > 0.5
+	//     class 1 (1.000 of 2)
+}
+
+func ExamplePGM7_RandomForest() {
+	r := rand.New(rand.NewSource(98765))
+	trainFeatures, trainLabels := readTrainData()
+	benchmarkFeatures, benchmarkLabels := readBenchmarkData()
+
+	for _, size := range []int{1, 10, 100} {
+		fmt.Println("---\nsize: ", size)
+		o := ai.RandomForestOptions{EnsembleOptions: ai.EnsembleOptions{Size: size}}
+		forest, err := ai.TrainRandomForest(trainFeatures, nominalLabels(trainLabels), 2, o, r)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("out-of-bag: %.3f\n", forest.OOBAccuracy())
+		fmt.Println("train set: ", ai.EvaluateNominalClassifier(forest, trainFeatures, nominalLabels(trainLabels), 2).Accuracy)
+		fmt.Println("benchmark set: ", ai.EvaluateNominalClassifier(forest, benchmarkFeatures, nominalLabels(benchmarkLabels), 2).Accuracy)
+
+		if size == 100 {
+			importance := ai.PermutationImportance(forest, benchmarkFeatures, nominalLabels(benchmarkLabels), 5, r)
+			for n := 0; n < 3; n++ {
+				j := 0
+				for k := range importance {
+					if importance[k] > importance[j] {
+						j = k
+					}
+				}
+				fmt.Printf("x%d importance: %.3f\n", j, importance[j])
+				importance[j] = -1
+			}
+		}
+	}
+
+	// Output:
+	// ---
+	// size:  1
+	// out-of-bag: 0.824
+	// train set:  0.935
+	// benchmark set:  0.86
+	// ---
+	// size:  10
+	// out-of-bag: 0.838
+	// train set:  1
+	// benchmark set:  0.96
+	// ---
+	// size:  100
+	// out-of-bag: 0.920
+	// train set:  1
+	// benchmark set:  0.96
+	// x49 importance: 0.005
+	// x107 importance: 0.005
+	// x122 importance: 0.005
+}
+
+func ExamplePGM7_AdaBoost() {
+	trainFeatures, trainLabels := readTrainData()
+	benchmarkFeatures, benchmarkLabels := readBenchmarkData()
+
+	for _, kind := range []ai.AdaBoostKind{ai.DiscreteAdaBoost, ai.RealAdaBoost} {
+		fmt.Println("---\nkind: ", kind)
+		classifier := ai.TrainAdaBoostClassifier(
+			trainFeatures,
+			trainLabels,
+			ai.NewDecisionStumpTrainer(ai.GiniCriterion),
+			ai.AdaBoostOptions{Kind: kind, Rounds: 50})
+		errors := classifier.TrainingErrors()
+		fmt.Printf("training error after 1, 10, 50 rounds: %.3f %.3f %.3f\n", errors[0], errors[9], errors[49])
+		fmt.Println("train set: ", ai.EvaluateBinaryClassifier(classifier, trainFeatures, trainLabels))
+		fmt.Println("benchmark set: ", ai.EvaluateBinaryClassifier(classifier, benchmarkFeatures, benchmarkLabels))
+	}
+
+	// Output:
+	// ---
+	// kind:  discrete
+	// training error after 1, 10, 50 rounds: 0.305 0.055 0.000
+	// train set:  1
+	// benchmark set:  0.935
+	// ---
+	// kind:  real
+	// training error after 1, 10, 50 rounds: 0.305 0.045 0.000
+	// train set:  1
+	// benchmark set:  0.945
+}
+
+func ExamplePGM7_LinearSVM() {
+	r := rand.New(rand.NewSource(98765))
+	trainFeatures, trainLabels := readTrainData()
+	benchmarkFeatures, benchmarkLabels := readBenchmarkData()
+
+	for _, loss := range []ai.SVMLoss{ai.HingeLoss, ai.SquaredHingeLoss} {
+		for _, lambda := range []float64{0.01, 0.1, 1} {
+			fmt.Println("---\nloss: ", loss, "lambda: ", lambda)
+			o := ai.LinearSVMOptions{Lambda: lambda, Loss: loss, PlattScaling: true}
+			classifier := ai.TrainLinearSVMClassifierWithRand(
+				trainFeatures,
+				trainLabels,
+				o,
+				&sgrad.NumIterationsCrit{NumIterations: 10},
+				1e-8,
+				r)
+			fmt.Println("train set: ", ai.EvaluateBinaryClassifier(classifier, trainFeatures, trainLabels))
+			report := ai.EvaluateBinaryClassifierReport(classifier, benchmarkFeatures, benchmarkLabels)
+			fmt.Printf("benchmark set: %v log-loss: %.3f\n", report.Accuracy, report.LogLoss)
+		}
+	}
+
+	// Output:
+	// ---
+	// loss:  hinge lambda:  0.01
+	// train set:  1
+	// benchmark set: 0.925 log-loss: 0.187
+	// ---
+	// loss:  hinge lambda:  0.1
+	// train set:  0.965
+	// benchmark set: 0.915 log-loss: 0.212
+	// ---
+	// loss:  hinge lambda:  1
+	// train set:  0.92
+	// benchmark set: 0.915 log-loss: 0.223
+	// ---
+	// loss:  squared hinge lambda:  0.01
+	// train set:  0.885
+	// benchmark set: 0.845 log-loss: 0.293
+	// ---
+	// loss:  squared hinge lambda:  0.1
+	// train set:  1
+	// benchmark set: 0.925 log-loss: 0.203
+	// ---
+	// loss:  squared hinge lambda:  1
+	// train set:  0.955
+	// benchmark set: 0.925 log-loss: 0.203
+}
+
+func ExamplePGM7_PCA() {
+	r := rand.New(rand.NewSource(98765))
+	trainFeatures, trainLabels := readTrainData()
+	benchmarkFeatures, benchmarkLabels := readBenchmarkData()
+
+	// Feature 0 is the bias term, it is added back after the projection.
+	withoutBias := func(features []v.F64) (result []v.F64) {
+		for _, f := range features {
+			result = append(result, f[1:])
+		}
+		return
+	}
+	trainFeatures, benchmarkFeatures = withoutBias(trainFeatures), withoutBias(benchmarkFeatures)
+
+	p := pca.New(pca.Options{VarianceRatio: 0.9})
+	p.Fit(trainFeatures)
+	fmt.Println("components explaining 90% of variance: ", p.OutputDim())
+
+	for _, components := range []int{5, 10, 20, 50} {
+		fmt.Println("---\ncomponents: ", components)
+		trainer := preprocess.BinaryTrainer(
+			func() preprocess.Transformer {
+				return preprocess.NewPipeline(pca.New(pca.Options{Components: components}), preprocess.NewBias())
+			},
+			ai.NewLogisticRegressionRandTrainer(0,
+				&sgrad.NumIterationsCrit{NumIterations: 10},
+				1e-8).WithRand(r))
+		classifier := trainer(trainFeatures, trainLabels)
+		fmt.Println("train set: ", ai.EvaluateBinaryClassifier(classifier, trainFeatures, trainLabels))
+		fmt.Println("benchmark set: ", ai.EvaluateBinaryClassifier(classifier, benchmarkFeatures, benchmarkLabels))
+	}
+
+	// Output:
+	// components explaining 90% of variance:  51
+	// ---
+	// components:  5
+	// train set:  0.9
+	// benchmark set:  0.905
+	// ---
+	// components:  10
+	// train set:  0.91
+	// benchmark set:  0.865
+	// ---
+	// components:  20
+	// train set:  0.94
+	// benchmark set:  0.905
+	// ---
+	// components:  50
+	// train set:  0.985
+	// benchmark set:  0.92
+}
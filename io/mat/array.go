@@ -3,6 +3,7 @@ package mat
 
 import (
 	"fmt"
+	"github.com/deboshire/exp/math/matrix"
 	"github.com/deboshire/exp/math/vector"
 )
 
@@ -12,24 +13,17 @@ type Array struct {
 	Data []float64
 }
 
-func (a *Array) RowsToVectors() (vectors []vector.F64) {
+// 2-dimensional array as a matrix sharing its data, which MATLAB stores in
+// column-major order.
+func (a *Array) Matrix() *matrix.Dense {
 	if len(a.Dim) != 2 {
 		panic(fmt.Sprintf("Array is not 2-dimensional: %v", a.Dim))
 	}
+	return matrix.NewColMajor(int(a.Dim[0]), int(a.Dim[1]), a.Data)
+}
 
-	rows := int(a.Dim[0])
-	rowLen := int(a.Dim[1])
-	data := a.Data
-
-	for i := 0; i < rows; i++ {
-		row := make([]float64, rowLen)
-		for j := 0; j < rowLen; j++ {
-			row[j] = data[i + j * rows]
-		}
-		vectors = append(vectors, row)
-	}
-
-	return
+func (a *Array) RowsToVectors() []vector.F64 {
+	return a.Matrix().RowVectors()
 }
 
 func (a *Array) ToVector() vector.F64 {
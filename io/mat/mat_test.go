@@ -65,4 +65,8 @@ func TestFail(t *testing.T) {
 		1e-10) {
 		t.Fatalf("vector mismatch: %#v", vectors[0])
 	}
+
+	if m := arr.Matrix(); m.Rows() != 200 || m.Cols() != 129 || !m.Row(0).Eq(vectors[0], 0) {
+		t.Fatalf("matrix mismatch: %v", m.Row(0))
+	}
 }
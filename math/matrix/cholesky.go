@@ -0,0 +1,101 @@
+package matrix
+
+import (
+	"fmt"
+	"github.com/deboshire/exp/math/vector"
+	"math"
+)
+
+// Cholesky decomposition A = L*L^T of a symmetric positive definite matrix.
+type Cholesky struct {
+	l *Dense
+}
+
+// Uses the lower triangle of a. ok is false if a is not positive definite.
+func NewCholesky(a *Dense) (c *Cholesky, ok bool) {
+	checkSquare(a)
+	n := a.rows
+	l := New(n, n)
+	d := l.data
+
+	for i := 0; i < n; i++ {
+		for j := 0; j <= i; j++ {
+			sum := a.At(i, j)
+			for k := 0; k < j; k++ {
+				sum -= d[i*n+k] * d[j*n+k]
+			}
+			if i != j {
+				d[i*n+j] = sum / d[j*n+j]
+				continue
+			}
+			if sum <= 0 || math.IsNaN(sum) {
+				return nil, false
+			}
+			d[i*n+i] = math.Sqrt(sum)
+		}
+	}
+	return &Cholesky{l}, true
+}
+
+// Lower triangular factor, shared with c.
+func (c *Cholesky) L() *Dense {
+	return c.l
+}
+
+func (c *Cholesky) LogDet() float64 {
+	n := c.l.rows
+	logDet := 0.0
+	for k := 0; k < n; k++ {
+		logDet += 2 * math.Log(c.l.data[k*n+k])
+	}
+	return logDet
+}
+
+func (c *Cholesky) Det() float64 {
+	return math.Exp(c.LogDet())
+}
+
+// y with L*y = b.
+func (c *Cholesky) SolveLVec(b vector.F64) vector.F64 {
+	n := c.l.rows
+	if len(b) != n {
+		panic(fmt.Sprintf("Right-hand side has %d elements, expected %d", len(b), n))
+	}
+	l := c.l.data
+	y := b.Copy()
+	for i := 0; i < n; i++ {
+		sum := y[i]
+		for k := 0; k < i; k++ {
+			sum -= l[i*n+k] * y[k]
+		}
+		y[i] = sum / l[i*n+i]
+	}
+	return y
+}
+
+// x with A*x = b.
+func (c *Cholesky) SolveVec(b vector.F64) vector.F64 {
+	n := c.l.rows
+	l := c.l.data
+	x := c.SolveLVec(b)
+	for i := n - 1; i >= 0; i-- {
+		sum := x[i]
+		for k := i + 1; k < n; k++ {
+			sum -= l[k*n+i] * x[k]
+		}
+		x[i] = sum / l[i*n+i]
+	}
+	return x
+}
+
+// X with A*X = B.
+func (c *Cholesky) Solve(b *Dense) *Dense {
+	x := New(b.rows, b.cols)
+	for j := 0; j < b.cols; j++ {
+		col := c.SolveVec(b.Col(j))
+		for i, v := range col {
+			x.data[i*b.cols+j] = v
+		}
+	}
+	return x
+}
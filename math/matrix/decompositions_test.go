@@ -0,0 +1,153 @@
+package matrix
+
+import (
+	"github.com/deboshire/exp/math/vector"
+	"math"
+	"testing"
+)
+
+var (
+	spd = FromRows([]vector.F64{{4, 2, 2}, {2, 5, 3}, {2, 3, 6}})
+	// Needs pivoting: zero in the top left corner.
+	general = FromRows([]vector.F64{{0, 2, 1}, {1, 1, 1}, {2, 1, 3}})
+	tall    = FromRows([]vector.F64{{1, 1}, {1, 2}, {1, 3}, {1, 4}})
+)
+
+func isOrthonormal(q *Dense, eps float64) bool {
+	return Mul(q.T(), q).Eq(Identity(q.cols), eps)
+}
+
+func TestLU(t *testing.T) {
+	f := NewLU(general)
+	pa := New(3, 3)
+	for i, p := range f.Pivot() {
+		pa.RowView(i).CopyFrom(general.RowView(p))
+	}
+	if lu := Mul(f.L(), f.U()); !lu.Eq(pa, 1e-12) {
+		t.Errorf("L*U:\n%vP*A:\n%v", lu, pa)
+	}
+
+	if d := Det(general); math.Abs(d-(-3)) > 1e-12 {
+		t.Errorf("Determinant %g", d)
+	}
+	if p := Mul(general, Inverse(general)); !p.Eq(Identity(3), 1e-12) {
+		t.Errorf("A*inverse(A):\n%v", p)
+	}
+
+	b := vector.F64{3, 3, 6}
+	if x := f.SolveVec(b); !general.MulVec(x).Eq(b, 1e-12) {
+		t.Errorf("Bad solution %v", x)
+	}
+
+	singular := FromRows([]vector.F64{{1, 2}, {2, 4}})
+	if !NewLU(singular).Singular() || Det(singular) != 0 {
+		t.Errorf("Singular matrix not detected")
+	}
+}
+
+func TestCholesky(t *testing.T) {
+	c, ok := NewCholesky(spd)
+	if !ok {
+		t.Fatal("Positive definite matrix rejected")
+	}
+	expected := FromRows([]vector.F64{{2, 0, 0}, {1, 2, 0}, {1, 1, 2}})
+	if !c.L().Eq(expected, 1e-12) {
+		t.Errorf("L:\n%v", c.L())
+	}
+	if math.Abs(c.Det()-Det(spd)) > 1e-9 || math.Abs(c.LogDet()-math.Log(64)) > 1e-12 {
+		t.Errorf("Determinant %g, log %g", c.Det(), c.LogDet())
+	}
+
+	b := vector.F64{2, 1, 4}
+	if y := c.SolveLVec(b); !y.Eq(vector.F64{1, 0, 1.5}, 1e-12) {
+		t.Errorf("Solution of L*y = b: %v", y)
+	}
+	if x := c.SolveVec(b); !spd.MulVec(x).Eq(b, 1e-12) {
+		t.Errorf("Bad solution %v", x)
+	}
+	if x := c.Solve(Identity(3)); !x.Eq(Inverse(spd), 1e-12) {
+		t.Errorf("Bad inverse:\n%v", x)
+	}
+
+	if _, ok := NewCholesky(general); ok {
+		t.Error("Matrix that is not positive definite accepted")
+	}
+}
+
+func TestQR(t *testing.T) {
+	for _, a := range []*Dense{general, tall} {
+		f := NewQR(a)
+		if !isOrthonormal(f.Q(), 1e-12) {
+			t.Errorf("Q is not orthonormal:\n%v", f.Q())
+		}
+		if qr := Mul(f.Q(), f.R()); !qr.Eq(a, 1e-12) {
+			t.Errorf("Q*R:\n%vA:\n%v", qr, a)
+		}
+		r := f.R()
+		for i := 0; i < r.rows; i++ {
+			for j := 0; j < i; j++ {
+				if r.At(i, j) != 0 {
+					t.Errorf("R is not upper triangular:\n%v", r)
+				}
+			}
+		}
+	}
+
+	// Line through (1, 6), (2, 5), (3, 7), (4, 10): y = 3.5 + 1.4x.
+	if x := NewQR(tall).SolveVec(vector.F64{6, 5, 7, 10}); !x.Eq(vector.F64{3.5, 1.4}, 1e-12) {
+		t.Errorf("Least squares solution %v", x)
+	}
+	if x := NewQR(general).Solve(Identity(3)); !x.Eq(Inverse(general), 1e-12) {
+		t.Errorf("Bad inverse:\n%v", x)
+	}
+}
+
+func TestSymmetricEigen(t *testing.T) {
+	e := NewSymmetricEigen(FromRows([]vector.F64{{2, 1}, {1, 2}}))
+	if !e.Values.Eq(vector.F64{3, 1}, 1e-12) {
+		t.Errorf("Eigenvalues %v", e.Values)
+	}
+
+	e = NewSymmetricEigen(spd)
+	if !isOrthonormal(e.Vectors, 1e-12) {
+		t.Errorf("Eigenvectors are not orthonormal:\n%v", e.Vectors)
+	}
+	for k, lambda := range e.Values {
+		x := e.Vectors.Col(k)
+		ax := spd.MulVec(x)
+		x.Mul(lambda)
+		if !ax.Eq(x, 1e-12) {
+			t.Errorf("A*x = %v, lambda*x = %v", ax, x)
+		}
+	}
+}
+
+func TestSVD(t *testing.T) {
+	for _, a := range []*Dense{general, tall, spd.T()} {
+		s := NewSVD(a)
+		if !isOrthonormal(s.U, 1e-12) || !isOrthonormal(s.V, 1e-12) {
+			t.Errorf("Singular vectors are not orthonormal:\n%v%v", s.U, s.V)
+		}
+		usv := s.U.Copy()
+		for j := 0; j < usv.cols; j++ {
+			usv.ColView(j).Scale(s.Values[j])
+		}
+		if usv = Mul(usv, s.V.T()); !usv.Eq(a, 1e-12) {
+			t.Errorf("U*S*V^T:\n%vA:\n%v", usv, a)
+		}
+	}
+
+	// Singular values of a symmetric positive definite matrix are its
+	// eigenvalues.
+	if s, e := NewSVD(spd), NewSymmetricEigen(spd); !s.Values.Eq(e.Values, 1e-12) {
+		t.Errorf("Singular values %v, eigenvalues %v", s.Values, e.Values)
+	}
+
+	if c := Cond(FromRows([]vector.F64{{2, 0}, {0, 0.5}})); math.Abs(c-4) > 1e-12 {
+		t.Errorf("Condition number %g", c)
+	}
+	singular := NewSVD(FromRows([]vector.F64{{1, 2}, {2, 4}}))
+	if singular.Rank(1e-12) != 1 || !math.IsInf(singular.Cond(), 1) {
+		t.Errorf("Singular values of singular matrix: %v", singular.Values)
+	}
+}
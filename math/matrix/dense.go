@@ -0,0 +1,264 @@
+/*
+	Dense matrices.
+
+	Element (i, j) of a matrix lives at i*rowStride + j*colStride of its
+	storage, so transposes and submatrices are views sharing storage with
+	the original matrix, and both row-major and column-major data can be
+	used without copying. Modifying a view modifies the original.
+*/
+package matrix
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/deboshire/exp/math/vector"
+	"math"
+)
+
+type Dense struct {
+	rows, cols           int
+	rowStride, colStride int
+	data                 vector.F64
+}
+
+// Zero matrix.
+func New(rows, cols int) *Dense {
+	return NewFromData(rows, cols, vector.Zeroes(rows*cols))
+}
+
+// Matrix over data in row-major order, without copying.
+func NewFromData(rows, cols int, data vector.F64) *Dense {
+	checkSize(rows, cols, data)
+	return &Dense{rows: rows, cols: cols, rowStride: cols, colStride: 1, data: data}
+}
+
+// Matrix over data in column-major order, as used by MATLAB and Fortran,
+// without copying.
+func NewColMajor(rows, cols int, data vector.F64) *Dense {
+	checkSize(rows, cols, data)
+	return &Dense{rows: rows, cols: cols, rowStride: 1, colStride: rows, data: data}
+}
+
+func checkSize(rows, cols int, data vector.F64) {
+	if rows < 0 || cols < 0 || len(data) != rows*cols {
+		panic(fmt.Sprintf("%d elements for %dx%d matrix", len(data), rows, cols))
+	}
+}
+
+// Matrix with copies of the given vectors as rows.
+func FromRows(rows []vector.F64) *Dense {
+	if len(rows) == 0 {
+		return New(0, 0)
+	}
+	m := New(len(rows), len(rows[0]))
+	for i, row := range rows {
+		if len(row) != m.cols {
+			panic(fmt.Sprintf("Row %d has %d elements, expected %d", i, len(row), m.cols))
+		}
+		copy(m.data[i*m.cols:], row)
+	}
+	return m
+}
+
+func Identity(n int) *Dense {
+	m := New(n, n)
+	for i := 0; i < n; i++ {
+		m.data[i*n+i] = 1
+	}
+	return m
+}
+
+func (m *Dense) Rows() int { return m.rows }
+func (m *Dense) Cols() int { return m.cols }
+
+func (m *Dense) index(i, j int) int {
+	if i < 0 || i >= m.rows || j < 0 || j >= m.cols {
+		panic(fmt.Sprintf("Index (%d, %d) out of %dx%d matrix", i, j, m.rows, m.cols))
+	}
+	return i*m.rowStride + j*m.colStride
+}
+
+func (m *Dense) At(i, j int) float64 {
+	return m.data[m.index(i, j)]
+}
+
+func (m *Dense) Set(i, j int, x float64) {
+	m.data[m.index(i, j)] = x
+}
+
+// Transposed view.
+func (m *Dense) T() *Dense {
+	return &Dense{rows: m.cols, cols: m.rows, rowStride: m.colStride, colStride: m.rowStride, data: m.data}
+}
+
+// View of the rows x cols submatrix starting at (i, j).
+func (m *Dense) View(i, j, rows, cols int) *Dense {
+	if i < 0 || j < 0 || rows < 0 || cols < 0 || i+rows > m.rows || j+cols > m.cols {
+		panic(fmt.Sprintf("View %dx%d at (%d, %d) out of %dx%d matrix", rows, cols, i, j, m.rows, m.cols))
+	}
+	if rows == 0 || cols == 0 {
+		return &Dense{rows: rows, cols: cols, rowStride: m.rowStride, colStride: m.colStride}
+	}
+	return &Dense{rows: rows, cols: cols, rowStride: m.rowStride, colStride: m.colStride, data: m.data[m.index(i, j):]}
+}
+
+// 1 x cols view of row i.
+func (m *Dense) RowView(i int) *Dense {
+	return m.View(i, 0, 1, m.cols)
+}
+
+// rows x 1 view of column j.
+func (m *Dense) ColView(j int) *Dense {
+	return m.View(0, j, m.rows, 1)
+}
+
+// Copy of row i.
+func (m *Dense) Row(i int) vector.F64 {
+	if m.cols == 0 {
+		if i < 0 || i >= m.rows {
+			panic(fmt.Sprintf("Row %d out of %dx%d matrix", i, m.rows, m.cols))
+		}
+		return vector.F64{}
+	}
+	if m.colStride == 1 {
+		return m.data[m.index(i, 0) : m.index(i, 0)+m.cols].Copy()
+	}
+	row := vector.Zeroes(m.cols)
+	for j := range row {
+		row[j] = m.At(i, j)
+	}
+	return row
+}
+
+// Copy of column j.
+func (m *Dense) Col(j int) vector.F64 {
+	return m.T().Row(j)
+}
+
+// Copies of all rows.
+func (m *Dense) RowVectors() []vector.F64 {
+	rows := make([]vector.F64, m.rows)
+	for i := range rows {
+		rows[i] = m.Row(i)
+	}
+	return rows
+}
+
+// Row-major copy.
+func (m *Dense) Copy() *Dense {
+	c := New(m.rows, m.cols)
+	c.CopyFrom(m)
+	return c
+}
+
+func (m *Dense) checkSameSize(other *Dense) {
+	if m.rows != other.rows || m.cols != other.cols {
+		panic(fmt.Sprintf("Matrix sizes differ: %dx%d and %dx%d", m.rows, m.cols, other.rows, other.cols))
+	}
+}
+
+// Sets elements of m to those of other.
+func (m *Dense) CopyFrom(other *Dense) {
+	m.checkSameSize(other)
+	for i := 0; i < m.rows; i++ {
+		for j := 0; j < m.cols; j++ {
+			m.Set(i, j, other.At(i, j))
+		}
+	}
+}
+
+// Adds other to m.
+func (m *Dense) Add(other *Dense) {
+	m.checkSameSize(other)
+	for i := 0; i < m.rows; i++ {
+		for j := 0; j < m.cols; j++ {
+			m.data[m.index(i, j)] += other.At(i, j)
+		}
+	}
+}
+
+// Subtracts other from m.
+func (m *Dense) Sub(other *Dense) {
+	m.checkSameSize(other)
+	for i := 0; i < m.rows; i++ {
+		for j := 0; j < m.cols; j++ {
+			m.data[m.index(i, j)] -= other.At(i, j)
+		}
+	}
+}
+
+// Multiplies every element of m by s.
+func (m *Dense) Scale(s float64) {
+	for i := 0; i < m.rows; i++ {
+		for j := 0; j < m.cols; j++ {
+			m.data[m.index(i, j)] *= s
+		}
+	}
+}
+
+// Matrix product a*b.
+func Mul(a, b *Dense) *Dense {
+	if a.cols != b.rows {
+		panic(fmt.Sprintf("Can not multiply %dx%d and %dx%d matrices", a.rows, a.cols, b.rows, b.cols))
+	}
+
+	result := New(a.rows, b.cols)
+	for i := 0; i < a.rows; i++ {
+		row := result.data[i*b.cols : (i+1)*b.cols]
+		for k := 0; k < a.cols; k++ {
+			aik := a.data[i*a.rowStride+k*a.colStride]
+			if aik == 0 {
+				continue
+			}
+			start := k * b.rowStride
+			for j := range row {
+				row[j] += aik * b.data[start+j*b.colStride]
+			}
+		}
+	}
+	return result
+}
+
+// Matrix-vector product m*x.
+func (m *Dense) MulVec(x vector.F64) vector.F64 {
+	if len(x) != m.cols {
+		panic(fmt.Sprintf("Can not multiply %dx%d matrix by vector of %d elements", m.rows, m.cols, len(x)))
+	}
+
+	result := vector.Zeroes(m.rows)
+	for i := range result {
+		if m.colStride == 1 {
+			start := i * m.rowStride
+			result[i] = m.data[start : start+m.cols].DotProduct(x)
+			continue
+		}
+		for j, xj := range x {
+			result[i] += m.data[i*m.rowStride+j*m.colStride] * xj
+		}
+	}
+	return result
+}
+
+// True if sizes are equal and elements differ by at most eps.
+func (m *Dense) Eq(other *Dense, eps float64) bool {
+	if m.rows != other.rows || m.cols != other.cols {
+		return false
+	}
+	for i := 0; i < m.rows; i++ {
+		for j := 0; j < m.cols; j++ {
+			if math.Abs(m.At(i, j)-other.At(i, j)) > eps {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Rows on separate lines.
+func (m *Dense) String() string {
+	var b bytes.Buffer
+	for i := 0; i < m.rows; i++ {
+		fmt.Fprintln(&b, m.Row(i))
+	}
+	return b.String()
+}
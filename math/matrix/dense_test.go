@@ -0,0 +1,82 @@
+package matrix
+
+import (
+	"github.com/deboshire/exp/math/vector"
+	"testing"
+)
+
+func TestLayouts(t *testing.T) {
+	rowMajor := NewFromData(2, 3, vector.F64{1, 2, 3, 4, 5, 6})
+	colMajor := NewColMajor(2, 3, vector.F64{1, 4, 2, 5, 3, 6})
+	rows := FromRows([]vector.F64{{1, 2, 3}, {4, 5, 6}})
+
+	for _, m := range []*Dense{rowMajor, colMajor, rows} {
+		if m.Rows() != 2 || m.Cols() != 3 || m.At(1, 0) != 4 || m.At(0, 2) != 3 {
+			t.Errorf("Bad matrix:\n%v", m)
+		}
+		if !m.Row(1).Eq(vector.F64{4, 5, 6}, 0) || !m.Col(1).Eq(vector.F64{2, 5}, 0) {
+			t.Errorf("Bad row or column:\n%v", m)
+		}
+		if !m.Eq(rowMajor, 0) || !m.T().T().Eq(rowMajor, 0) {
+			t.Errorf("Matrices differ:\n%v\n%v", m, rowMajor)
+		}
+	}
+
+	if colMajor.T().At(2, 1) != 6 || colMajor.T().Rows() != 3 {
+		t.Errorf("Bad transpose:\n%v", colMajor.T())
+	}
+
+	empty := FromRows([]vector.F64{{}, {}})
+	if rows := empty.RowVectors(); len(rows) != 2 || len(rows[1]) != 0 || len(empty.T().Col(0)) != 0 {
+		t.Errorf("Bad rows of a matrix without columns: %v", rows)
+	}
+}
+
+func TestViews(t *testing.T) {
+	data := vector.F64{1, 2, 3, 4, 5, 6, 7, 8, 9}
+	m := NewFromData(3, 3, data)
+
+	v := m.View(1, 1, 2, 2)
+	if !v.Eq(FromRows([]vector.F64{{5, 6}, {8, 9}}), 0) {
+		t.Errorf("Bad view:\n%v", v)
+	}
+
+	// Views and the data share storage.
+	v.T().Set(1, 0, 0)
+	m.ColView(0).Scale(10)
+	m.RowView(0).Add(NewFromData(1, 3, vector.F64{1, 1, 1}))
+	if !data.Eq(vector.F64{11, 3, 4, 40, 5, 0, 70, 8, 9}, 0) {
+		t.Errorf("Bad data after changing views: %v", data)
+	}
+
+	c := m.T().Copy()
+	c.Sub(m.T())
+	if !c.Eq(New(3, 3), 0) {
+		t.Errorf("Copy differs:\n%v", c)
+	}
+}
+
+func TestMul(t *testing.T) {
+	a := FromRows([]vector.F64{{1, 2, 3}, {4, 5, 6}})
+	b := NewColMajor(3, 2, vector.F64{7, 9, 11, 8, 10, 12})
+
+	expected := FromRows([]vector.F64{{58, 64}, {139, 154}})
+	if p := Mul(a, b); !p.Eq(expected, 0) {
+		t.Errorf("Bad product:\n%v", p)
+	}
+	// (AB)^T = B^T A^T
+	if p := Mul(b.T(), a.T()); !p.Eq(expected.T(), 0) {
+		t.Errorf("Bad transposed product:\n%v", p)
+	}
+	if p := Mul(Identity(2), a); !p.Eq(a, 0) {
+		t.Errorf("Bad product with identity:\n%v", p)
+	}
+
+	x := vector.F64{1, 0, -1}
+	if y := a.MulVec(x); !y.Eq(vector.F64{-2, -2}, 0) {
+		t.Errorf("Bad matrix-vector product: %v", y)
+	}
+	if y := b.T().MulVec(x); !y.Eq(vector.F64{-4, -4}, 0) {
+		t.Errorf("Bad transposed matrix-vector product: %v", y)
+	}
+}
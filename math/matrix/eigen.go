@@ -0,0 +1,123 @@
+package matrix
+
+import (
+	"github.com/deboshire/exp/math/vector"
+	"math"
+	"sort"
+)
+
+const (
+	jacobiMaxSweeps = 100
+	jacobiEps       = 1e-14
+)
+
+// Eigen-decomposition A = V*diag(Values)*V^T of a symmetric matrix.
+type Eigen struct {
+	// In order of decreasing value.
+	Values vector.F64
+	// Orthonormal eigenvectors as columns, in the order of Values.
+	Vectors *Dense
+}
+
+// Decomposes a symmetric matrix by the cyclic Jacobi method. Only the
+// lower triangle of a is used.
+func NewSymmetricEigen(a *Dense) *Eigen {
+	checkSquare(a)
+	n := a.rows
+	s := New(n, n)
+	for i := 0; i < n; i++ {
+		for j := 0; j <= i; j++ {
+			s.data[i*n+j] = a.At(i, j)
+			s.data[j*n+i] = a.At(i, j)
+		}
+	}
+	d := s.data
+
+	// Rows of q are the eigenvectors.
+	q := Identity(n).data
+
+	for sweep := 0; sweep < jacobiMaxSweeps; sweep++ {
+		offDiagonal, diagonal := 0.0, 0.0
+		for i := 0; i < n; i++ {
+			diagonal += d[i*n+i] * d[i*n+i]
+			for j := i + 1; j < n; j++ {
+				offDiagonal += d[i*n+j] * d[i*n+j]
+			}
+		}
+		if offDiagonal <= jacobiEps*jacobiEps*diagonal {
+			break
+		}
+
+		for p := 0; p < n; p++ {
+			for r := p + 1; r < n; r++ {
+				apr := d[p*n+r]
+				if apr == 0 {
+					continue
+				}
+
+				// Rotation zeroing a[p][r], see Golub and Van Loan 8.4.
+				theta := (d[r*n+r] - d[p*n+p]) / (2 * apr)
+				t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				if theta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+
+				for k := 0; k < n; k++ {
+					akp, akr := d[k*n+p], d[k*n+r]
+					d[k*n+p] = c*akp - s*akr
+					d[k*n+r] = s*akp + c*akr
+				}
+				for k := 0; k < n; k++ {
+					apk, ark := d[p*n+k], d[r*n+k]
+					d[p*n+k] = c*apk - s*ark
+					d[r*n+k] = s*apk + c*ark
+				}
+				rotate(q[p*n:(p+1)*n], q[r*n:(r+1)*n], c, s)
+			}
+		}
+	}
+
+	values := vector.Zeroes(n)
+	for i := range values {
+		values[i] = d[i*n+i]
+	}
+	order := decreasingOrder(values)
+	e := &Eigen{Values: vector.Zeroes(n), Vectors: New(n, n)}
+	for k, i := range order {
+		e.Values[k] = values[i]
+		for j := 0; j < n; j++ {
+			e.Vectors.data[j*n+k] = q[i*n+j]
+		}
+	}
+	return e
+}
+
+// Replaces x and y with c*x - s*y and s*x + c*y.
+func rotate(x, y vector.F64, c, s float64) {
+	for k := range x {
+		a, b := x[k], y[k]
+		x[k] = c*a - s*b
+		y[k] = s*a + c*b
+	}
+}
+
+// Indices of values in order of decreasing value.
+func decreasingOrder(values vector.F64) []int {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Sort(&byDecreasingValue{order, values})
+	return order
+}
+
+type byDecreasingValue struct {
+	order  []int
+	values vector.F64
+}
+
+func (s *byDecreasingValue) Len() int           { return len(s.order) }
+func (s *byDecreasingValue) Less(i, j int) bool { return s.values[s.order[i]] > s.values[s.order[j]] }
+func (s *byDecreasingValue) Swap(i, j int)      { s.order[i], s.order[j] = s.order[j], s.order[i] }
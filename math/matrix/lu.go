@@ -0,0 +1,162 @@
+package matrix
+
+import (
+	"fmt"
+	"github.com/deboshire/exp/math/vector"
+	"math"
+)
+
+// LU decomposition with partial pivoting: P*A = L*U with L unit lower
+// triangular and U upper triangular.
+type LU struct {
+	// L below the diagonal, U on and above it, row-major.
+	lu    *Dense
+	pivot []int
+	// Determinant of P.
+	sign float64
+}
+
+func checkSquare(a *Dense) {
+	if a.rows != a.cols {
+		panic(fmt.Sprintf("Matrix is not square: %dx%d", a.rows, a.cols))
+	}
+}
+
+func NewLU(a *Dense) *LU {
+	checkSquare(a)
+	n := a.rows
+	lu := a.Copy()
+	d := lu.data
+	f := &LU{lu: lu, pivot: make([]int, n), sign: 1}
+	for i := range f.pivot {
+		f.pivot[i] = i
+	}
+
+	for k := 0; k < n; k++ {
+		p := k
+		for i := k + 1; i < n; i++ {
+			if math.Abs(d[i*n+k]) > math.Abs(d[p*n+k]) {
+				p = i
+			}
+		}
+		if p != k {
+			for j := 0; j < n; j++ {
+				d[p*n+j], d[k*n+j] = d[k*n+j], d[p*n+j]
+			}
+			f.pivot[p], f.pivot[k] = f.pivot[k], f.pivot[p]
+			f.sign = -f.sign
+		}
+
+		if d[k*n+k] == 0 {
+			continue
+		}
+		for i := k + 1; i < n; i++ {
+			d[i*n+k] /= d[k*n+k]
+			for j := k + 1; j < n; j++ {
+				d[i*n+j] -= d[i*n+k] * d[k*n+j]
+			}
+		}
+	}
+	return f
+}
+
+// True if U has a zero on the diagonal.
+func (f *LU) Singular() bool {
+	n := f.lu.rows
+	for k := 0; k < n; k++ {
+		if f.lu.data[k*n+k] == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *LU) L() *Dense {
+	n := f.lu.rows
+	l := Identity(n)
+	for i := 0; i < n; i++ {
+		copy(l.data[i*n:i*n+i], f.lu.data[i*n:i*n+i])
+	}
+	return l
+}
+
+func (f *LU) U() *Dense {
+	n := f.lu.rows
+	u := New(n, n)
+	for i := 0; i < n; i++ {
+		copy(u.data[i*n+i:(i+1)*n], f.lu.data[i*n+i:(i+1)*n])
+	}
+	return u
+}
+
+// Row i of P*A is row Pivot()[i] of A.
+func (f *LU) Pivot() []int {
+	return append([]int(nil), f.pivot...)
+}
+
+func (f *LU) Det() float64 {
+	n := f.lu.rows
+	det := f.sign
+	for k := 0; k < n; k++ {
+		det *= f.lu.data[k*n+k]
+	}
+	return det
+}
+
+// X with A*X = b. Panics if A is singular.
+func (f *LU) Solve(b *Dense) *Dense {
+	n := f.lu.rows
+	if b.rows != n {
+		panic(fmt.Sprintf("Right-hand side has %d rows, expected %d", b.rows, n))
+	}
+	if f.Singular() {
+		panic("Matrix is singular")
+	}
+
+	m := b.cols
+	x := New(n, m)
+	for i, p := range f.pivot {
+		for j := 0; j < m; j++ {
+			x.data[i*m+j] = b.At(p, j)
+		}
+	}
+
+	lu := f.lu.data
+	for k := 0; k < n; k++ {
+		for i := k + 1; i < n; i++ {
+			for j := 0; j < m; j++ {
+				x.data[i*m+j] -= x.data[k*m+j] * lu[i*n+k]
+			}
+		}
+	}
+	for k := n - 1; k >= 0; k-- {
+		for j := 0; j < m; j++ {
+			x.data[k*m+j] /= lu[k*n+k]
+		}
+		for i := 0; i < k; i++ {
+			for j := 0; j < m; j++ {
+				x.data[i*m+j] -= x.data[k*m+j] * lu[i*n+k]
+			}
+		}
+	}
+	return x
+}
+
+// x with A*x = b. Panics if A is singular.
+func (f *LU) SolveVec(b vector.F64) vector.F64 {
+	return f.Solve(NewFromData(len(b), 1, b)).data
+}
+
+// X with A*X = B for square A. Panics if A is singular.
+func Solve(a, b *Dense) *Dense {
+	return NewLU(a).Solve(b)
+}
+
+// Panics if a is singular.
+func Inverse(a *Dense) *Dense {
+	return NewLU(a).Solve(Identity(a.rows))
+}
+
+func Det(a *Dense) float64 {
+	return NewLU(a).Det()
+}
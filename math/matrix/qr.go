@@ -0,0 +1,147 @@
+package matrix
+
+import (
+	"fmt"
+	"github.com/deboshire/exp/math/vector"
+	"math"
+)
+
+// QR decomposition A = Q*R of a matrix with at least as many rows as
+// columns by Householder reflections. Q has orthonormal columns and R is
+// square upper triangular.
+type QR struct {
+	// Householder vectors on and below the diagonal, R above it.
+	qr    *Dense
+	rDiag vector.F64
+}
+
+func NewQR(a *Dense) *QR {
+	m, n := a.rows, a.cols
+	if m < n {
+		panic(fmt.Sprintf("QR needs at least as many rows as columns: %dx%d", m, n))
+	}
+	qr := a.Copy()
+	d := qr.data
+	f := &QR{qr: qr, rDiag: vector.Zeroes(n)}
+
+	for k := 0; k < n; k++ {
+		norm := 0.0
+		for i := k; i < m; i++ {
+			norm = math.Hypot(norm, d[i*n+k])
+		}
+		if norm != 0 {
+			if d[k*n+k] < 0 {
+				norm = -norm
+			}
+			for i := k; i < m; i++ {
+				d[i*n+k] /= norm
+			}
+			d[k*n+k] += 1
+
+			for j := k + 1; j < n; j++ {
+				s := 0.0
+				for i := k; i < m; i++ {
+					s += d[i*n+k] * d[i*n+j]
+				}
+				s = -s / d[k*n+k]
+				for i := k; i < m; i++ {
+					d[i*n+j] += s * d[i*n+k]
+				}
+			}
+		}
+		f.rDiag[k] = -norm
+	}
+	return f
+}
+
+// True if R has no zeros on the diagonal.
+func (f *QR) FullRank() bool {
+	for _, x := range f.rDiag {
+		if x == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *QR) R() *Dense {
+	n := f.qr.cols
+	r := New(n, n)
+	for i := 0; i < n; i++ {
+		r.data[i*n+i] = f.rDiag[i]
+		copy(r.data[i*n+i+1:(i+1)*n], f.qr.data[i*n+i+1:(i+1)*n])
+	}
+	return r
+}
+
+// Q with as many columns as A.
+func (f *QR) Q() *Dense {
+	m, n := f.qr.rows, f.qr.cols
+	d := f.qr.data
+	q := New(m, n)
+	for k := n - 1; k >= 0; k-- {
+		q.data[k*n+k] = 1
+		for j := k; j < n; j++ {
+			if d[k*n+k] == 0 {
+				continue
+			}
+			s := 0.0
+			for i := k; i < m; i++ {
+				s += d[i*n+k] * q.data[i*n+j]
+			}
+			s = -s / d[k*n+k]
+			for i := k; i < m; i++ {
+				q.data[i*n+j] += s * d[i*n+k]
+			}
+		}
+	}
+	return q
+}
+
+// X minimizing the Frobenius norm of A*X - B, the solution of A*X = B for
+// square A. Panics unless A has full rank.
+func (f *QR) Solve(b *Dense) *Dense {
+	m, n := f.qr.rows, f.qr.cols
+	if b.rows != m {
+		panic(fmt.Sprintf("Right-hand side has %d rows, expected %d", b.rows, m))
+	}
+	if !f.FullRank() {
+		panic("Matrix is rank deficient")
+	}
+
+	d := f.qr.data
+	cols := b.cols
+	x := b.Copy()
+	xd := x.data
+
+	// Q^T * B
+	for k := 0; k < n; k++ {
+		for j := 0; j < cols; j++ {
+			s := 0.0
+			for i := k; i < m; i++ {
+				s += d[i*n+k] * xd[i*cols+j]
+			}
+			s = -s / d[k*n+k]
+			for i := k; i < m; i++ {
+				xd[i*cols+j] += s * d[i*n+k]
+			}
+		}
+	}
+	// R * X = Q^T * B
+	for k := n - 1; k >= 0; k-- {
+		for j := 0; j < cols; j++ {
+			xd[k*cols+j] /= f.rDiag[k]
+		}
+		for i := 0; i < k; i++ {
+			for j := 0; j < cols; j++ {
+				xd[i*cols+j] -= xd[k*cols+j] * d[i*n+k]
+			}
+		}
+	}
+	return x.View(0, 0, n, cols).Copy()
+}
+
+// Least squares solution of A*x = b. Panics unless A has full rank.
+func (f *QR) SolveVec(b vector.F64) vector.F64 {
+	return f.Solve(NewFromData(len(b), 1, b)).data
+}
@@ -0,0 +1,110 @@
+package matrix
+
+import (
+	"github.com/deboshire/exp/math/vector"
+	"math"
+)
+
+// Thin singular value decomposition A = U*diag(Values)*V^T.
+type SVD struct {
+	// Non-negative, in decreasing order.
+	Values vector.F64
+	// Left singular vectors as columns, as many as A has columns. Columns
+	// for zero singular values are zero.
+	U *Dense
+	// Right singular vectors as columns.
+	V *Dense
+}
+
+// Decomposes a by the one-sided Jacobi method (Hestenes), which rotates
+// pairs of columns until they are orthogonal.
+func NewSVD(a *Dense) *SVD {
+	m, n := a.rows, a.cols
+	// Column-major copy, so that columns are contiguous.
+	w := vector.Zeroes(m * n)
+	for j := 0; j < n; j++ {
+		for i := 0; i < m; i++ {
+			w[j*m+i] = a.At(i, j)
+		}
+	}
+	column := func(j int) vector.F64 { return w[j*m : (j+1)*m] }
+
+	// Rows of q are the right singular vectors.
+	q := Identity(n).data
+
+	for sweep := 0; sweep < jacobiMaxSweeps; sweep++ {
+		rotated := false
+		for p := 0; p < n; p++ {
+			for r := p + 1; r < n; r++ {
+				cp, cr := column(p), column(r)
+				alpha := cp.DotProduct(cp)
+				beta := cr.DotProduct(cr)
+				gamma := cp.DotProduct(cr)
+				if gamma == 0 || math.Abs(gamma) <= jacobiEps*math.Sqrt(alpha*beta) {
+					continue
+				}
+				rotated = true
+
+				// Rotation making columns p and r orthogonal.
+				zeta := (beta - alpha) / (2 * gamma)
+				t := 1 / (math.Abs(zeta) + math.Sqrt(1+zeta*zeta))
+				if zeta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(1+t*t)
+				s := c * t
+
+				rotate(cp, cr, c, s)
+				rotate(q[p*n:(p+1)*n], q[r*n:(r+1)*n], c, s)
+			}
+		}
+		if !rotated {
+			break
+		}
+	}
+
+	values := vector.Zeroes(n)
+	for j := range values {
+		values[j] = math.Sqrt(column(j).DotProduct(column(j)))
+	}
+	order := decreasingOrder(values)
+	svd := &SVD{Values: vector.Zeroes(n), U: New(m, n), V: New(n, n)}
+	for k, j := range order {
+		svd.Values[k] = values[j]
+		for i := 0; i < n; i++ {
+			svd.V.data[i*n+k] = q[j*n+i]
+		}
+		if values[j] == 0 {
+			continue
+		}
+		for i, x := range column(j) {
+			svd.U.data[i*n+k] = x / values[j]
+		}
+	}
+	return svd
+}
+
+// Number of singular values above tol times the largest one.
+func (s *SVD) Rank(tol float64) int {
+	rank := 0
+	for _, x := range s.Values {
+		if x > tol*s.Values[0] {
+			rank++
+		}
+	}
+	return rank
+}
+
+// Ratio of the largest singular value to the smallest, +Inf for singular
+// matrices.
+func (s *SVD) Cond() float64 {
+	if len(s.Values) == 0 {
+		return 0
+	}
+	return s.Values[0] / s.Values[len(s.Values)-1]
+}
+
+// Condition number in 2-norm.
+func Cond(a *Dense) float64 {
+	return NewSVD(a).Cond()
+}
@@ -15,6 +15,36 @@ import (
 type ObjectiveFunc struct {
 	Terms int
 	F     func(idx int, x vector.F64, out_gradient vector.F64) float64
+	// Optional replacement of F for terms whose gradients have few
+	// non-zeros. out_gradient may keep its storage between calls, or have
+	// its slices replaced. Only the stored coordinates of x are updated, so
+	// a step costs the number of non-zeros rather than the dimension.
+	SparseF func(idx int, x vector.F64, out_gradient *vector.Sparse) float64
+	// Optional, called after every pass with the step sizes of its updates,
+	// e.g. to apply a regularization term once per pass instead of at every
+	// update.
+	EndPass func(x vector.F64, alphas []float64)
+}
+
+// Optional parameters of the minimization process. The zero value gives
+// the behaviour of Minimize.
+type Options struct {
+	// Source of the term permutations. Global math/rand source is used if nil.
+	Rand *rand.Rand
+	// Step size for the given update, steps counted from 1 across all
+	// passes. Default is .1/(1+sqrt(pass)), traced once per pass; a custom
+	// rate is traced on every step.
+	Rate func(pass int, step int) float64
+	// Called on x after every update, e.g. to project it back onto the
+	// feasible set.
+	Project func(x vector.F64)
+}
+
+func (o *Options) perm(n int) []int {
+	if o.Rand == nil {
+		return rand.Perm(n)
+	}
+	return o.Rand.Perm(n)
 }
 
 type State struct {
@@ -81,47 +111,77 @@ func (c *NumIterationsCrit) ShouldTerminate(s *State) float64 {
 		Sum_i{F_i(x)}, i := 0...terms
 */
 func Minimize(f ObjectiveFunc, initial vector.F64, eps float64, term TermCrit, t tracer.Tracer) (value float64, coords vector.F64) {
+	return MinimizeWithOptions(f, initial, eps, term, t, Options{})
+}
+
+// Same as Minimize, but with explicit options.
+func MinimizeWithOptions(f ObjectiveFunc, initial vector.F64, eps float64, term TermCrit, t tracer.Tracer, o Options) (value float64, coords vector.F64) {
 	if t == nil {
 		t = tracer.DefaultTracer()
 	}
 
 	s := State{Pass: 0, Tracer: t}
 	x := initial.Copy()
-	grad := initial.Copy()
+	var grad vector.F64
+	var sparseGrad vector.Sparse
+	if f.SparseF == nil {
+		grad = vector.Zeroes(len(initial))
+	}
+	var alphas []float64
+	step := 0
 
 	for pass := 0; ; pass++ {
 		s.Pass = pass
-		perm := rand.Perm(f.Terms)
+		perm := o.perm(f.Terms)
 		maxDist := 0.0
+		alphas = alphas[:0]
 
 		// todo(mike): there's some theory about choosing alpha.
 		// http://leon.bottou.org/slides/largescale/lstut.pdf
 		alpha := .1 / (1 + math.Sqrt(float64(pass)))
-		t.TraceFloat64("alpha", alpha)
+		if o.Rate == nil {
+			t.TraceFloat64("alpha", alpha)
+		}
 
 		for _, idx := range perm {
+			step++
+			if o.Rate != nil {
+				alpha = o.Rate(pass, step)
+				t.TraceFloat64("alpha", alpha)
+			}
 			t.TraceInt("idx", idx)
 
 			t.TraceF64("x", x)
 
-			y:= f.F(idx, x, grad)
-			t.TraceF64("grad", grad)
+			var y, dist float64
+			if f.SparseF != nil {
+				y = f.SparseF(idx, x, &sparseGrad)
+				t.TraceF64("sparseGrad", sparseGrad.Values)
+				sparseGrad.Axpy(-alpha, x)
+				dist = alpha * alpha * sparseGrad.Values.DotProduct(sparseGrad.Values)
+			} else {
+				y = f.F(idx, x, grad)
+				t.TraceF64("grad", grad)
+				x.Axpy(-alpha, grad)
+				dist = alpha * alpha * grad.DotProduct(grad)
+			}
 			t.TraceFloat64("y", y)
+			alphas = append(alphas, alpha)
 
-			grad.Mul(-alpha)
-			grad.Add(x)
-
-			dist := x.Dist2(grad)
 			if dist > maxDist {
 				maxDist = dist
 			}
-			temp := x
-			x = grad
-			grad = temp
 			value = y
+
+			if o.Project != nil {
+				o.Project(x)
+			}
 		}
 
 		t.TraceFloat64("maxDist", maxDist)
+		if f.EndPass != nil {
+			f.EndPass(x, alphas)
+		}
 
 		s.Value = value
 		err := term.ShouldTerminate(&s)
@@ -134,6 +194,30 @@ func Minimize(f ObjectiveFunc, initial vector.F64, eps float64, term TermCrit, t
 	return value, x
 }
 
+// Largest difference between the gradient of term idx at x computed by f and
+// its central finite difference estimate with step h, relative to the
+// magnitude of the derivatives if it is above 1. Meant for testing gradient
+// implementations; x is left unchanged.
+func CheckGradient(f ObjectiveFunc, idx int, x vector.F64, h float64) (maxError float64) {
+	gradient := vector.Zeroes(len(x))
+	f.F(idx, x, gradient)
+
+	scratch := vector.Zeroes(len(x))
+	for i := range x {
+		orig := x[i]
+		x[i] = orig + h
+		plus := f.F(idx, x, scratch)
+		x[i] = orig - h
+		minus := f.F(idx, x, scratch)
+		x[i] = orig
+
+		estimate := (plus - minus) / (2 * h)
+		diff := math.Abs(estimate-gradient[i]) / math.Max(1, math.Abs(estimate)+math.Abs(gradient[i]))
+		maxError = math.Max(maxError, diff)
+	}
+	return
+}
+
 /*
 	Objective function for performing least squares optimization.
 */
@@ -45,6 +45,67 @@ func TestLeastSquares(t *testing.T) {
 	}
 }
 
+func TestRate(t *testing.T) {
+	f := LeastSquares([]vector.F64{
+		vector.F64{1, 1},
+		vector.F64{2, 2},
+	})
+
+	var steps []int
+	rate := func(pass, step int) float64 {
+		steps = append(steps, step)
+		return 0
+	}
+	_, coords := MinimizeWithOptions(f, vector.F64{3, 4}, 1e-8, &NumIterationsCrit{NumIterations: 3}, nil, Options{Rate: rate})
+
+	if !coords.Eq(vector.F64{3, 4}, 0) {
+		t.Error("Zero rate moved coords:", coords)
+	}
+	if len(steps) != 6 || steps[0] != 1 || steps[5] != 6 {
+		t.Error("Bad steps:", steps)
+	}
+}
+
+func TestProject(t *testing.T) {
+	f := LeastSquares([]vector.F64{
+		vector.F64{1, 1},
+		vector.F64{2, 2},
+	})
+
+	// Keeps x[0] at 0, the least squares solution then stays x[1] = 1.
+	project := func(x vector.F64) { x[0] = 0 }
+	term := RelativeMeanImprovementCrit{NumItersToAvg: 10}
+	_, coords := MinimizeWithOptions(f, vector.Zeroes(2), 1e-10, &term, nil, Options{Project: project})
+
+	if coords[0] != 0 || math.Abs(coords[1]-1) > 1e-2 {
+		t.Error("Bad coords:", coords)
+	}
+}
+
+func TestSparseGradient(t *testing.T) {
+	// Term i is (x[i] - i)^2, its gradient has a single non-zero.
+	f := ObjectiveFunc{Terms: 5}
+	f.SparseF = func(idx int, x vector.F64, gradient *vector.Sparse) float64 {
+		d := x[idx] - float64(idx)
+		*gradient = vector.NewSparse(len(x), []int{idx}, vector.F64{2 * d})
+		return d * d
+	}
+	var passes []int
+	f.EndPass = func(x vector.F64, alphas []float64) {
+		passes = append(passes, len(alphas))
+	}
+
+	rate := func(pass, step int) float64 { return 0.25 }
+	_, coords := MinimizeWithOptions(f, vector.Zeroes(5), 1e-8, &NumIterationsCrit{NumIterations: 50}, nil, Options{Rate: rate})
+
+	if !coords.Eq(vector.F64{0, 1, 2, 3, 4}, 1e-3) {
+		t.Error("Bad coords:", coords)
+	}
+	if len(passes) != 50 || passes[0] != 5 || passes[49] != 5 {
+		t.Error("Bad passes:", passes)
+	}
+}
+
 func BenchmarkLeastSquare(b *testing.B) {
 	f := LeastSquares([]vector.F64{
 		vector.F64{1, 1},
@@ -61,3 +122,31 @@ func BenchmarkLeastSquare(b *testing.B) {
 func init() {
 	rand.Seed(1)
 }
+
+func TestCheckGradient(t *testing.T) {
+	// Term idx is (x.p)^2 for point p.
+	points := []vector.F64{{1, 2, 3}, {-1, 0.5, 2}}
+	f := ObjectiveFunc{Terms: len(points), F: func(idx int, x vector.F64, gradient vector.F64) float64 {
+		a := x.DotProduct(points[idx])
+		points[idx].CopyTo(gradient)
+		gradient.Mul(2 * a)
+		return a * a
+	}}
+	x := vector.F64{0.3, -0.2, 0.7}
+
+	if err := CheckGradient(f, 1, x, 1e-5); err > 1e-8 {
+		t.Errorf("Gradient error %g for correct gradient", err)
+	}
+	if x[0] != 0.3 || x[1] != -0.2 || x[2] != 0.7 {
+		t.Errorf("x changed: %v", x)
+	}
+
+	broken := ObjectiveFunc{Terms: f.Terms, F: func(idx int, x vector.F64, gradient vector.F64) float64 {
+		value := f.F(idx, x, gradient)
+		gradient[2] *= 2
+		return value
+	}}
+	if err := CheckGradient(broken, 1, x, 1e-5); err < 0.1 {
+		t.Errorf("Gradient error %g for broken gradient", err)
+	}
+}
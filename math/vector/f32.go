@@ -0,0 +1,262 @@
+package vector
+
+import (
+	"fmt"
+	"math"
+)
+
+// Single precision vector, for data too large to keep in F64. Operations
+// mirror those of F64. Reductions accumulate in float64 and return
+// float64, so only storage is single precision.
+type F32 []float32
+
+func assertSameLen32(v1 F32, v2 F32) {
+	if len(v1) != len(v2) {
+		panic(fmt.Sprintf("Length mismatch: %d != %d", len(v1), len(v2)))
+	}
+}
+
+func assertSameLenF64(v1 F32, v2 F64) {
+	if len(v1) != len(v2) {
+		panic(fmt.Sprintf("Length mismatch: %d != %d", len(v1), len(v2)))
+	}
+}
+
+func assertNotEmpty32(v F32) {
+	if len(v) == 0 {
+		panic("Empty vector")
+	}
+}
+
+func Zeroes32(size int) F32 {
+	return F32(make([]float32, size))
+}
+
+// Copy of v rounded to single precision.
+func (v F64) ToF32() F32 {
+	result := Zeroes32(len(v))
+	for i, x := range v {
+		result[i] = float32(x)
+	}
+	return result
+}
+
+func (v F32) ToF64() F64 {
+	result := Zeroes(len(v))
+	for i, x := range v {
+		result[i] = float64(x)
+	}
+	return result
+}
+
+func (v F32) Copy() F32 {
+	result := Zeroes32(len(v))
+	copy(result, v)
+	return result
+}
+
+func (v F32) CopyTo(target F32) {
+	copy(target, v)
+}
+
+func (v F32) Len() int {
+	return len(v)
+}
+
+func (v F32) Sub(v1 F32) {
+	assertSameLen32(v, v1)
+	for i := range v {
+		v[i] -= v1[i]
+	}
+}
+
+func (v F32) Add(v1 F32) {
+	assertSameLen32(v, v1)
+	for i := range v {
+		v[i] += v1[i]
+	}
+}
+
+func (v F32) Mul(s float64) {
+	for i := range v {
+		v[i] = float32(float64(v[i]) * s)
+	}
+}
+
+// Adds a*x to v, rounding only the result.
+func (v F32) Axpy(a float64, x F32) {
+	assertSameLen32(v, x)
+	x = x[:len(v)]
+	for i := range v {
+		v[i] = float32(float64(v[i]) + a*float64(x[i]))
+	}
+}
+
+func (v F32) MulElem(v1 F32) {
+	assertSameLen32(v, v1)
+	for i := range v {
+		v[i] *= v1[i]
+	}
+}
+
+func (v F32) DivElem(v1 F32) {
+	assertSameLen32(v, v1)
+	for i := range v {
+		v[i] /= v1[i]
+	}
+}
+
+// Replaces every element x of v with f(x), computed in double precision.
+func (v F32) Apply(f func(float64) float64) {
+	for i, x := range v {
+		v[i] = float32(f(float64(x)))
+	}
+}
+
+func (v F32) DotProduct(v1 F32) float64 {
+	assertSameLen32(v, v1)
+	v1 = v1[:len(v)]
+	result := 0.0
+	for i, x := range v {
+		result += float64(x) * float64(v1[i])
+	}
+	return result
+}
+
+// Dot product with a double precision vector.
+func (v F32) DotProductF64(v1 F64) float64 {
+	assertSameLenF64(v, v1)
+	v1 = v1[:len(v)]
+	result := 0.0
+	for i, x := range v {
+		result += float64(x) * v1[i]
+	}
+	return result
+}
+
+func (v F32) Dist2(v1 F32) float64 {
+	assertSameLen32(v, v1)
+	v1 = v1[:len(v)]
+	result := 0.0
+	for i, x := range v {
+		d := float64(x) - float64(v1[i])
+		result += d * d
+	}
+	return result
+}
+
+func (v F32) Eq(v1 F32, eps float64) bool {
+	if len(v) != len(v1) {
+		return false
+	}
+
+	for i := range v {
+		if math.Abs(float64(v[i])-float64(v1[i])) > eps {
+			return false
+		}
+	}
+	return true
+}
+
+func (v F32) Sum() float64 {
+	result := 0.0
+	for _, x := range v {
+		result += float64(x)
+	}
+	return result
+}
+
+func (v F32) Mean() float64 {
+	assertNotEmpty32(v)
+	return v.Sum() / float64(len(v))
+}
+
+// Population variance: mean squared deviation from the mean.
+func (v F32) Variance() float64 {
+	mean := v.Mean()
+	result := 0.0
+	for _, x := range v {
+		d := float64(x) - mean
+		result += d * d
+	}
+	return result / float64(len(v))
+}
+
+func (v F32) Norm1() float64 {
+	result := 0.0
+	for _, x := range v {
+		result += math.Abs(float64(x))
+	}
+	return result
+}
+
+func (v F32) Norm2() float64 {
+	return math.Sqrt(v.DotProduct(v))
+}
+
+func (v F32) NormInf() float64 {
+	result := 0.0
+	for _, x := range v {
+		result = math.Max(result, math.Abs(float64(x)))
+	}
+	return result
+}
+
+func (v F32) Min() float32 {
+	return v[v.ArgMin()]
+}
+
+func (v F32) Max() float32 {
+	return v[v.ArgMax()]
+}
+
+// Index of the smallest element, the first one on ties.
+func (v F32) ArgMin() (result int) {
+	assertNotEmpty32(v)
+	for i := range v {
+		if v[i] < v[result] {
+			result = i
+		}
+	}
+	return
+}
+
+// Index of the largest element, the first one on ties.
+func (v F32) ArgMax() (result int) {
+	assertNotEmpty32(v)
+	for i := range v {
+		if v[i] > v[result] {
+			result = i
+		}
+	}
+	return
+}
+
+// log(sum(exp(v))) without overflow. -Inf if all elements are -Inf.
+func (v F32) LogSumExp() float64 {
+	max := float64(v.Max())
+	if math.IsInf(max, -1) {
+		return max
+	}
+
+	sum := 0.0
+	for _, x := range v {
+		sum += math.Exp(float64(x) - max)
+	}
+	return max + math.Log(sum)
+}
+
+// Same as F64.Softmax.
+func (v F32) Softmax() (logSumExp float64) {
+	max := float64(v.Max())
+
+	sum := 0.0
+	for _, x := range v {
+		sum += math.Exp(float64(x) - max)
+	}
+	for i, x := range v {
+		v[i] = float32(math.Exp(float64(x)-max) / sum)
+	}
+
+	return max + math.Log(sum)
+}
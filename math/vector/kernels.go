@@ -0,0 +1,96 @@
+package vector
+
+// Kernels are the loops behind DotProduct, Dist2 and Axpy. Every backend
+// sums in the same order, so that results do not depend on the machine or
+// build tags: eight partial sums, element i going to sum i%8, combined as
+// ((s0+s4)+(s2+s6))+((s1+s5)+(s3+s7)), then the remaining len%8 elements
+// added one by one. Products are rounded before being added, never fused.
+//
+// The pure Go backend is always available. On amd64 processors with AVX2
+// an assembly backend is used instead, unless built with the purego tag.
+// Building with the vector_cgo tag selects a C backend; Go does not allow
+// assembly in packages using cgo, so that build has no AVX2 backend.
+type kernels struct {
+	name  string
+	dot   func(x, y []float64) float64
+	dist2 func(x, y []float64) float64
+	// Adds a*x to y.
+	axpy func(a float64, x, y []float64)
+}
+
+var goKernels = &kernels{"go", dotGo, dist2Go, axpyGo}
+
+// Backends usable in this build and on this machine, preferred first.
+func availableKernels() []*kernels {
+	var result []*kernels
+	for _, k := range []*kernels{cgoKernels, avx2Kernels, goKernels} {
+		if k != nil {
+			result = append(result, k)
+		}
+	}
+	return result
+}
+
+var kernel = availableKernels()[0]
+
+func dotGo(x, y []float64) float64 {
+	y = y[:len(x)]
+	var s0, s1, s2, s3, s4, s5, s6, s7 float64
+	n := len(x) &^ 7
+	for i := 0; i < n; i += 8 {
+		a, b := x[i:i+8:i+8], y[i:i+8:i+8]
+		s0 += float64(a[0] * b[0])
+		s1 += float64(a[1] * b[1])
+		s2 += float64(a[2] * b[2])
+		s3 += float64(a[3] * b[3])
+		s4 += float64(a[4] * b[4])
+		s5 += float64(a[5] * b[5])
+		s6 += float64(a[6] * b[6])
+		s7 += float64(a[7] * b[7])
+	}
+	result := ((s0 + s4) + (s2 + s6)) + ((s1 + s5) + (s3 + s7))
+	for i := n; i < len(x); i++ {
+		result += float64(x[i] * y[i])
+	}
+	return result
+}
+
+func dist2Go(x, y []float64) float64 {
+	y = y[:len(x)]
+	var s0, s1, s2, s3, s4, s5, s6, s7 float64
+	n := len(x) &^ 7
+	for i := 0; i < n; i += 8 {
+		a, b := x[i:i+8:i+8], y[i:i+8:i+8]
+		d0, d1, d2, d3 := a[0]-b[0], a[1]-b[1], a[2]-b[2], a[3]-b[3]
+		d4, d5, d6, d7 := a[4]-b[4], a[5]-b[5], a[6]-b[6], a[7]-b[7]
+		s0 += float64(d0 * d0)
+		s1 += float64(d1 * d1)
+		s2 += float64(d2 * d2)
+		s3 += float64(d3 * d3)
+		s4 += float64(d4 * d4)
+		s5 += float64(d5 * d5)
+		s6 += float64(d6 * d6)
+		s7 += float64(d7 * d7)
+	}
+	result := ((s0 + s4) + (s2 + s6)) + ((s1 + s5) + (s3 + s7))
+	for i := n; i < len(x); i++ {
+		d := x[i] - y[i]
+		result += float64(d * d)
+	}
+	return result
+}
+
+func axpyGo(a float64, x, y []float64) {
+	y = y[:len(x)]
+	n := len(x) &^ 3
+	for i := 0; i < n; i += 4 {
+		u, v := x[i:i+4:i+4], y[i:i+4:i+4]
+		v[0] += float64(a * u[0])
+		v[1] += float64(a * u[1])
+		v[2] += float64(a * u[2])
+		v[3] += float64(a * u[3])
+	}
+	for i := n; i < len(x); i++ {
+		y[i] += float64(a * x[i])
+	}
+}
@@ -0,0 +1,36 @@
+//go:build !purego && !vector_cgo
+
+package vector
+
+func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+func xgetbv() (eax, edx uint32)
+
+func dotAVX2(x, y []float64) float64
+func dist2AVX2(x, y []float64) float64
+func axpyAVX2(a float64, x, y []float64)
+
+// Whether the processor supports AVX2 and the operating system saves YMM
+// registers on context switches.
+func hasAVX2() bool {
+	if maxID, _, _, _ := cpuid(0, 0); maxID < 7 {
+		return false
+	}
+	const osxsave, avx = 1 << 27, 1 << 28
+	if _, _, ecx, _ := cpuid(1, 0); ecx&osxsave == 0 || ecx&avx == 0 {
+		return false
+	}
+	// XMM and YMM state enabled in XCR0.
+	if eax, _ := xgetbv(); eax&6 != 6 {
+		return false
+	}
+	const avx2 = 1 << 5
+	_, ebx, _, _ := cpuid(7, 0)
+	return ebx&avx2 != 0
+}
+
+var avx2Kernels = func() *kernels {
+	if !hasAVX2() {
+		return nil
+	}
+	return &kernels{"avx2", dotAVX2, dist2AVX2, axpyAVX2}
+}()
@@ -0,0 +1,149 @@
+//go:build !purego && !vector_cgo
+
+#include "textflag.h"
+
+// func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+TEXT ·cpuid(SB), NOSPLIT, $0-24
+	MOVL eaxArg+0(FP), AX
+	MOVL ecxArg+4(FP), CX
+	CPUID
+	MOVL AX, eax+8(FP)
+	MOVL BX, ebx+12(FP)
+	MOVL CX, ecx+16(FP)
+	MOVL DX, edx+20(FP)
+	RET
+
+// func xgetbv() (eax, edx uint32)
+TEXT ·xgetbv(SB), NOSPLIT, $0-8
+	MOVL $0, CX
+	XGETBV
+	MOVL AX, eax+0(FP)
+	MOVL DX, edx+4(FP)
+	RET
+
+// Y0 holds partial sums 0-3 and Y1 sums 4-7, see kernels.go for the order
+// in which they are combined.
+
+// func dotAVX2(x, y []float64) float64
+TEXT ·dotAVX2(SB), NOSPLIT, $0-56
+	MOVQ x_base+0(FP), SI
+	MOVQ x_len+8(FP), CX
+	MOVQ y_base+24(FP), DI
+	MOVQ CX, BX
+	ANDQ $-8, BX
+	XORQ AX, AX
+	VXORPD Y0, Y0, Y0
+	VXORPD Y1, Y1, Y1
+
+dotLoop:
+	CMPQ AX, BX
+	JGE  dotReduce
+	VMOVUPD (SI)(AX*8), Y2
+	VMOVUPD 32(SI)(AX*8), Y3
+	VMULPD  (DI)(AX*8), Y2, Y2
+	VMULPD  32(DI)(AX*8), Y3, Y3
+	VADDPD  Y2, Y0, Y0
+	VADDPD  Y3, Y1, Y1
+	ADDQ    $8, AX
+	JMP     dotLoop
+
+dotReduce:
+	VADDPD       Y1, Y0, Y0
+	VEXTRACTF128 $1, Y0, X1
+	VADDPD       X1, X0, X0
+	VHADDPD      X0, X0, X0
+	VZEROUPPER
+
+dotTail:
+	CMPQ   AX, CX
+	JGE    dotDone
+	VMOVSD (SI)(AX*8), X2
+	VMULSD (DI)(AX*8), X2, X2
+	VADDSD X2, X0, X0
+	INCQ   AX
+	JMP    dotTail
+
+dotDone:
+	VMOVSD X0, ret+48(FP)
+	RET
+
+// func dist2AVX2(x, y []float64) float64
+TEXT ·dist2AVX2(SB), NOSPLIT, $0-56
+	MOVQ x_base+0(FP), SI
+	MOVQ x_len+8(FP), CX
+	MOVQ y_base+24(FP), DI
+	MOVQ CX, BX
+	ANDQ $-8, BX
+	XORQ AX, AX
+	VXORPD Y0, Y0, Y0
+	VXORPD Y1, Y1, Y1
+
+dist2Loop:
+	CMPQ AX, BX
+	JGE  dist2Reduce
+	VMOVUPD (SI)(AX*8), Y2
+	VMOVUPD 32(SI)(AX*8), Y3
+	VSUBPD  (DI)(AX*8), Y2, Y2
+	VSUBPD  32(DI)(AX*8), Y3, Y3
+	VMULPD  Y2, Y2, Y2
+	VMULPD  Y3, Y3, Y3
+	VADDPD  Y2, Y0, Y0
+	VADDPD  Y3, Y1, Y1
+	ADDQ    $8, AX
+	JMP     dist2Loop
+
+dist2Reduce:
+	VADDPD       Y1, Y0, Y0
+	VEXTRACTF128 $1, Y0, X1
+	VADDPD       X1, X0, X0
+	VHADDPD      X0, X0, X0
+	VZEROUPPER
+
+dist2Tail:
+	CMPQ   AX, CX
+	JGE    dist2Done
+	VMOVSD (SI)(AX*8), X2
+	VSUBSD (DI)(AX*8), X2, X2
+	VMULSD X2, X2, X2
+	VADDSD X2, X0, X0
+	INCQ   AX
+	JMP    dist2Tail
+
+dist2Done:
+	VMOVSD X0, ret+48(FP)
+	RET
+
+// func axpyAVX2(a float64, x, y []float64)
+TEXT ·axpyAVX2(SB), NOSPLIT, $0-56
+	VBROADCASTSD a+0(FP), Y0
+	MOVQ         x_base+8(FP), SI
+	MOVQ         x_len+16(FP), CX
+	MOVQ         y_base+32(FP), DI
+	MOVQ         CX, BX
+	ANDQ         $-8, BX
+	XORQ         AX, AX
+
+axpyLoop:
+	CMPQ    AX, BX
+	JGE     axpyTail
+	VMULPD  (SI)(AX*8), Y0, Y1
+	VMULPD  32(SI)(AX*8), Y0, Y2
+	VADDPD  (DI)(AX*8), Y1, Y1
+	VADDPD  32(DI)(AX*8), Y2, Y2
+	VMOVUPD Y1, (DI)(AX*8)
+	VMOVUPD Y2, 32(DI)(AX*8)
+	ADDQ    $8, AX
+	JMP     axpyLoop
+
+axpyTail:
+	CMPQ   AX, CX
+	JGE    axpyDone
+	VMULSD (SI)(AX*8), X0, X1
+	VADDSD (DI)(AX*8), X1, X1
+	VMOVSD X1, (DI)(AX*8)
+	INCQ   AX
+	JMP    axpyTail
+
+axpyDone:
+	VZEROUPPER
+	RET
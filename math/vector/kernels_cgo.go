@@ -0,0 +1,33 @@
+//go:build vector_cgo && cgo
+
+package vector
+
+import (
+	"unsafe"
+)
+
+// #cgo CFLAGS: -O3
+// #include "vector.h"
+import "C"
+
+// C pointer to the first element, nil for empty slices.
+func ptr(x []float64) *C.double {
+	if len(x) == 0 {
+		return nil
+	}
+	return (*C.double)(unsafe.Pointer(&x[0]))
+}
+
+func dotC(x, y []float64) float64 {
+	return float64(C.dot(ptr(x), ptr(y), C.int(len(x))))
+}
+
+func dist2C(x, y []float64) float64 {
+	return float64(C.dist2(ptr(x), ptr(y), C.int(len(x))))
+}
+
+func axpyC(a float64, x, y []float64) {
+	C.axpy(C.double(a), ptr(x), ptr(y), C.int(len(x)))
+}
+
+var cgoKernels = &kernels{"cgo", dotC, dist2C, axpyC}
@@ -0,0 +1,5 @@
+//go:build !amd64 || purego || vector_cgo
+
+package vector
+
+var avx2Kernels *kernels
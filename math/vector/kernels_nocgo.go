@@ -0,0 +1,5 @@
+//go:build !vector_cgo || !cgo
+
+package vector
+
+var cgoKernels *kernels
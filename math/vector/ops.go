@@ -0,0 +1,151 @@
+package vector
+
+import (
+	"math"
+)
+
+// None of the operations allocate. Reductions of empty vectors return 0,
+// except those without a meaningful value, which panic.
+
+func assertNotEmpty(v F64) {
+	if len(v) == 0 {
+		panic("Empty vector")
+	}
+}
+
+// Adds a*x to v.
+func (v F64) Axpy(a float64, x F64) {
+	assertSameLen(v, x)
+	kernel.axpy(a, x, v)
+}
+
+// Multiplies every element of v by the corresponding element of v1.
+func (v F64) MulElem(v1 F64) {
+	assertSameLen(v, v1)
+	for i := range v {
+		v[i] *= v1[i]
+	}
+}
+
+// Divides every element of v by the corresponding element of v1.
+func (v F64) DivElem(v1 F64) {
+	assertSameLen(v, v1)
+	for i := range v {
+		v[i] /= v1[i]
+	}
+}
+
+// Replaces every element x of v with f(x).
+func (v F64) Apply(f func(float64) float64) {
+	for i, x := range v {
+		v[i] = f(x)
+	}
+}
+
+func (v F64) Sum() float64 {
+	result := 0.0
+	for _, x := range v {
+		result += x
+	}
+	return result
+}
+
+func (v F64) Mean() float64 {
+	assertNotEmpty(v)
+	return v.Sum() / float64(len(v))
+}
+
+// Population variance: mean squared deviation from the mean.
+func (v F64) Variance() float64 {
+	mean := v.Mean()
+	result := 0.0
+	for _, x := range v {
+		result += (x - mean) * (x - mean)
+	}
+	return result / float64(len(v))
+}
+
+// Sum of absolute values.
+func (v F64) Norm1() float64 {
+	result := 0.0
+	for _, x := range v {
+		result += math.Abs(x)
+	}
+	return result
+}
+
+// Euclidean norm.
+func (v F64) Norm2() float64 {
+	return math.Sqrt(v.DotProduct(v))
+}
+
+// Largest absolute value.
+func (v F64) NormInf() float64 {
+	result := 0.0
+	for _, x := range v {
+		result = math.Max(result, math.Abs(x))
+	}
+	return result
+}
+
+func (v F64) Min() float64 {
+	return v[v.ArgMin()]
+}
+
+func (v F64) Max() float64 {
+	return v[v.ArgMax()]
+}
+
+// Index of the smallest element, the first one on ties.
+func (v F64) ArgMin() (result int) {
+	assertNotEmpty(v)
+	for i := range v {
+		if v[i] < v[result] {
+			result = i
+		}
+	}
+	return
+}
+
+// Index of the largest element, the first one on ties.
+func (v F64) ArgMax() (result int) {
+	assertNotEmpty(v)
+	for i := range v {
+		if v[i] > v[result] {
+			result = i
+		}
+	}
+	return
+}
+
+// log(sum(exp(v))) without overflow. -Inf if all elements are -Inf.
+func (v F64) LogSumExp() float64 {
+	assertNotEmpty(v)
+	max := v.Max()
+	if math.IsInf(max, -1) {
+		return max
+	}
+
+	sum := 0.0
+	for _, x := range v {
+		sum += math.Exp(x - max)
+	}
+	return max + math.Log(sum)
+}
+
+// Replaces v with exp(v) normalized to sum to 1, computed without
+// overflow. Returns log-sum-exp of the original v, so that the log of the
+// result is v minus it.
+func (v F64) Softmax() (logSumExp float64) {
+	assertNotEmpty(v)
+	max := v.Max()
+
+	sum := 0.0
+	for i, x := range v {
+		v[i] = math.Exp(x - max)
+		sum += v[i]
+	}
+	v.Mul(1 / sum)
+
+	return max + math.Log(sum)
+}
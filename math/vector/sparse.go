@@ -0,0 +1,138 @@
+package vector
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Vector of Dim elements of which only those at Indices are stored, all
+// others being zero. Indices are strictly increasing and Values[k] is the
+// element at Indices[k].
+type Sparse struct {
+	Dim     int
+	Indices []int
+	Values  F64
+}
+
+// Sparse vector with the given elements, in any order. Panics on repeated
+// or out of range indices.
+func NewSparse(dim int, indices []int, values F64) Sparse {
+	if len(indices) != len(values) {
+		panic(fmt.Sprintf("%d indices for %d values", len(indices), len(values)))
+	}
+
+	s := Sparse{Dim: dim, Indices: append([]int(nil), indices...), Values: values.Copy()}
+	sort.Sort(byIndex(s))
+	for k, i := range s.Indices {
+		if i < 0 || i >= dim {
+			panic(fmt.Sprintf("Index %d out of %d elements", i, dim))
+		}
+		if k > 0 && s.Indices[k-1] == i {
+			panic(fmt.Sprintf("Repeated index %d", i))
+		}
+	}
+	return s
+}
+
+type byIndex Sparse
+
+func (s byIndex) Len() int           { return len(s.Indices) }
+func (s byIndex) Less(i, j int) bool { return s.Indices[i] < s.Indices[j] }
+func (s byIndex) Swap(i, j int) {
+	s.Indices[i], s.Indices[j] = s.Indices[j], s.Indices[i]
+	s.Values[i], s.Values[j] = s.Values[j], s.Values[i]
+}
+
+// Sparse copy of v without its zeros.
+func (v F64) ToSparse() Sparse {
+	s := Sparse{Dim: len(v)}
+	for i, x := range v {
+		if x != 0 {
+			s.Indices = append(s.Indices, i)
+			s.Values = append(s.Values, x)
+		}
+	}
+	return s
+}
+
+func (s Sparse) ToDense() F64 {
+	v := Zeroes(s.Dim)
+	for k, i := range s.Indices {
+		v[i] = s.Values[k]
+	}
+	return v
+}
+
+func (s Sparse) Len() int {
+	return s.Dim
+}
+
+// Number of stored elements.
+func (s Sparse) NonZeros() int {
+	return len(s.Indices)
+}
+
+func (s Sparse) Copy() Sparse {
+	return Sparse{Dim: s.Dim, Indices: append([]int(nil), s.Indices...), Values: s.Values.Copy()}
+}
+
+// Element i.
+func (s Sparse) At(i int) float64 {
+	if i < 0 || i >= s.Dim {
+		panic(fmt.Sprintf("Index %d out of %d elements", i, s.Dim))
+	}
+	if k := sort.SearchInts(s.Indices, i); k < len(s.Indices) && s.Indices[k] == i {
+		return s.Values[k]
+	}
+	return 0
+}
+
+func (s Sparse) Mul(a float64) {
+	s.Values.Mul(a)
+}
+
+// Dot product with a dense vector, in time proportional to the number of
+// stored elements.
+func (s Sparse) DotProduct(v F64) float64 {
+	if s.Dim != len(v) {
+		panic(fmt.Sprintf("Length mismatch: %d != %d", s.Dim, len(v)))
+	}
+
+	result := 0.0
+	for k, i := range s.Indices {
+		result += s.Values[k] * v[i]
+	}
+	return result
+}
+
+// Dot product with another sparse vector.
+func (s Sparse) SparseDotProduct(s1 Sparse) float64 {
+	if s.Dim != s1.Dim {
+		panic(fmt.Sprintf("Length mismatch: %d != %d", s.Dim, s1.Dim))
+	}
+
+	result := 0.0
+	for k, k1 := 0, 0; k < len(s.Indices) && k1 < len(s1.Indices); {
+		switch i, i1 := s.Indices[k], s1.Indices[k1]; {
+		case i < i1:
+			k++
+		case i > i1:
+			k1++
+		default:
+			result += s.Values[k] * s1.Values[k1]
+			k++
+			k1++
+		}
+	}
+	return result
+}
+
+// Adds a*s to the dense vector y.
+func (s Sparse) Axpy(a float64, y F64) {
+	if s.Dim != len(y) {
+		panic(fmt.Sprintf("Length mismatch: %d != %d", s.Dim, len(y)))
+	}
+	for k, i := range s.Indices {
+		y[i] += a * s.Values[k]
+	}
+}
@@ -4,14 +4,8 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
-	"reflect"
-	"unsafe"
 )
 
-// #cgo CFLAGS:-O3 -ffast-math
-// #include "vector.h"
-import "C"
-
 type F64 []float64
 type B []bool
 
@@ -55,21 +49,9 @@ func (v F64) Mul(s float64) {
 	}
 }
 
-func addr(v F64) unsafe.Pointer {
-	header := *(*reflect.SliceHeader)(unsafe.Pointer(&v))
-	return unsafe.Pointer(header.Data)
-}
-
 func (v F64) Dist2(v1 F64) float64 {
 	assertSameLen(v, v1)
-	return float64(C.dist2(addr(v), addr(v1), C.int(len(v))))
-
-	// d := 0.0
-	// for i := range v {
-	// 	a := v[i] - v1[i]
-	// 	d += a * a
-	// }
-	// return d
+	return kernel.dist2(v, v1)
 }
 
 func (v F64) Len() int {
@@ -78,12 +60,7 @@ func (v F64) Len() int {
 
 func (v F64) DotProduct(v1 F64) float64 {
 	assertSameLen(v, v1)
-
-	result := 0.0
-	for i := range v {
-		result += v[i] * v1[i]
-	}
-	return result
+	return kernel.dot(v, v1)
 }
 
 func (v F64) F64ToB() B {
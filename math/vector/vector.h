@@ -1,17 +1,56 @@
 #pragma once
 
+// C kernels, summing in the order described in kernels.go. Products must
+// not be fused into additions, and there is no -ffast-math, which would let
+// the compiler reorder sums.
+#if defined(__clang__)
+#pragma STDC FP_CONTRACT OFF
+#elif defined(__GNUC__)
+#pragma GCC optimize("fp-contract=off")
+#endif
 
-double dist2(void* ap, void* bp, int len) {
-	int i;
-	double* a = (double*)ap;
-	double* b = (double*)bp;
+static double dot(const double* x, const double* y, int len) {
+	double s[8] = {0};
+	int n = len & ~7;
+	int i, k;
 
-	double result = 0.0;
+	for (i = 0; i < n; i += 8) {
+		for (k = 0; k < 8; ++k) {
+			s[k] += x[i + k] * y[i + k];
+		}
+	}
 
-	for (i = 0; i < len; ++i) {
-		double x = a[i] - b[i];
-		result += x * x;
+	double result = ((s[0] + s[4]) + (s[2] + s[6])) + ((s[1] + s[5]) + (s[3] + s[7]));
+	for (i = n; i < len; ++i) {
+		result += x[i] * y[i];
+	}
+	return result;
+}
+
+static double dist2(const double* x, const double* y, int len) {
+	double s[8] = {0};
+	int n = len & ~7;
+	int i, k;
+
+	for (i = 0; i < n; i += 8) {
+		for (k = 0; k < 8; ++k) {
+			double d = x[i + k] - y[i + k];
+			s[k] += d * d;
+		}
 	}
 
+	double result = ((s[0] + s[4]) + (s[2] + s[6])) + ((s[1] + s[5]) + (s[3] + s[7]));
+	for (i = n; i < len; ++i) {
+		double d = x[i] - y[i];
+		result += d * d;
+	}
 	return result;
 }
+
+static void axpy(double a, const double* x, double* y, int len) {
+	int i;
+
+	for (i = 0; i < len; ++i) {
+		y[i] += a * x[i];
+	}
+}
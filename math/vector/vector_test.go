@@ -1,6 +1,9 @@
 package vector
 
 import (
+	"fmt"
+	"math"
+	"math/rand"
 	"testing"
 )
 
@@ -13,7 +16,6 @@ func TestDist2(t *testing.T) {
 	}
 }
 
-
 func BenchmarkDist2(b *testing.B) {
 	v1 := Zeroes(10000)
 	v2 := Zeroes(10000)
@@ -22,3 +24,221 @@ func BenchmarkDist2(b *testing.B) {
 		v1.Dist2(v2)
 	}
 }
+
+func TestSparse(t *testing.T) {
+	s := NewSparse(6, []int{4, 1, 2}, F64{3, 1, 2})
+	if !s.ToDense().Eq(F64{0, 1, 2, 0, 3, 0}, 0) || s.NonZeros() != 3 || s.At(4) != 3 || s.At(5) != 0 {
+		t.Error("Bad sparse vector:", s)
+	}
+	if d := (F64{0, 1, 2, 0, 3, 0}).ToSparse(); d.Dim != 6 || !d.Values.Eq(s.Values, 0) || d.Indices[2] != 4 {
+		t.Error("Bad conversion to sparse:", d)
+	}
+
+	dense := F64{1, 2, 3, 4, 5, 6}
+	if p := s.DotProduct(dense); p != 2+6+15 {
+		t.Error("Bad dot product:", p)
+	}
+	if p := s.SparseDotProduct(NewSparse(6, []int{0, 2, 4, 5}, F64{7, 1, 2, 8})); p != 2+6 {
+		t.Error("Bad sparse dot product:", p)
+	}
+
+	s.Axpy(2, dense)
+	if !dense.Eq(F64{1, 4, 7, 4, 11, 6}, 0) {
+		t.Error("Bad axpy:", dense)
+	}
+}
+
+func TestOps(t *testing.T) {
+	v := F64{1, 2, 3}
+	v.Axpy(2, F64{1, -1, 0.5})
+	if !v.Eq(F64{3, 0, 4}, 0) {
+		t.Error("Bad axpy:", v)
+	}
+	v.MulElem(F64{2, 5, 0.5})
+	if !v.Eq(F64{6, 0, 2}, 0) {
+		t.Error("Bad elementwise product:", v)
+	}
+	v.DivElem(F64{3, 1, 4})
+	if !v.Eq(F64{2, 0, 0.5}, 0) {
+		t.Error("Bad elementwise quotient:", v)
+	}
+	v.Apply(func(x float64) float64 { return x - 1 })
+	if !v.Eq(F64{1, -1, -0.5}, 0) {
+		t.Error("Bad apply:", v)
+	}
+
+	w := F64{3, -4, 1, -4}
+	if w.Sum() != -4 || w.Mean() != -1 || w.Variance() != 9.5 {
+		t.Error("Bad moments:", w.Sum(), w.Mean(), w.Variance())
+	}
+	if w.Norm1() != 12 || w.Norm2() != math.Sqrt(42) || w.NormInf() != 4 {
+		t.Error("Bad norms:", w.Norm1(), w.Norm2(), w.NormInf())
+	}
+	if w.Min() != -4 || w.ArgMin() != 1 || w.Max() != 3 || w.ArgMax() != 0 {
+		t.Error("Bad extremes:", w.ArgMin(), w.ArgMax())
+	}
+}
+
+func TestLogSumExp(t *testing.T) {
+	v := F64{1000, 1000 + math.Log(3)}
+	if lse := v.LogSumExp(); math.Abs(lse-(1000+math.Log(4))) > 1e-12 {
+		t.Error("Bad log-sum-exp:", lse)
+	}
+	if lse := (F64{math.Inf(-1), math.Inf(-1)}).LogSumExp(); !math.IsInf(lse, -1) {
+		t.Error("Bad log-sum-exp of zero probabilities:", lse)
+	}
+
+	lse := v.Softmax()
+	if math.Abs(lse-(1000+math.Log(4))) > 1e-12 || !v.Eq(F64{0.25, 0.75}, 1e-12) {
+		t.Error("Bad softmax:", lse, v)
+	}
+}
+
+func benchmarkVectors() (F64, F64) {
+	v1 := Zeroes(10000)
+	v2 := Zeroes(10000)
+	for i := range v1 {
+		v1[i] = float64(i%7) - 3
+		v2[i] = float64(i%5) / 5
+	}
+	return v1, v2
+}
+
+func BenchmarkDotProduct(b *testing.B) {
+	v1, v2 := benchmarkVectors()
+	for i := 0; i < b.N; i++ {
+		v1.DotProduct(v2)
+	}
+}
+
+func BenchmarkAxpy(b *testing.B) {
+	v1, v2 := benchmarkVectors()
+	for i := 0; i < b.N; i++ {
+		v1.Axpy(1e-9, v2)
+	}
+}
+
+func BenchmarkNorm2(b *testing.B) {
+	v1, _ := benchmarkVectors()
+	for i := 0; i < b.N; i++ {
+		v1.Norm2()
+	}
+}
+
+func BenchmarkLogSumExp(b *testing.B) {
+	v1, _ := benchmarkVectors()
+	for i := 0; i < b.N; i++ {
+		v1.LogSumExp()
+	}
+}
+
+func BenchmarkSoftmax(b *testing.B) {
+	v1, _ := benchmarkVectors()
+	for i := 0; i < b.N; i++ {
+		v1.Softmax()
+	}
+}
+
+func TestF32(t *testing.T) {
+	v64 := F64{3, -4, 1, -4}
+	v := v64.ToF32()
+	if !v.ToF64().Eq(v64, 0) {
+		t.Error("Bad conversion:", v)
+	}
+	if v.Sum() != v64.Sum() || v.Variance() != v64.Variance() || v.Norm2() != v64.Norm2() || v.NormInf() != 4 {
+		t.Error("Bad reductions:", v.Sum(), v.Variance(), v.Norm2())
+	}
+	if v.Min() != -4 || v.ArgMin() != 1 || v.Max() != 3 || v.ArgMax() != 0 {
+		t.Error("Bad extremes:", v.ArgMin(), v.ArgMax())
+	}
+	if p := v.DotProductF64(F64{0.5, 0, 0, 1}); p != -2.5 {
+		t.Error("Bad dot product:", p)
+	}
+	if d := v.Dist2(F32{3, -4, 2, -2}); d != 5 {
+		t.Error("Bad distance:", d)
+	}
+
+	v.Axpy(0.5, F32{2, 2, 2, 2})
+	if !v.Eq(F32{4, -3, 2, -3}, 0) {
+		t.Error("Bad axpy:", v)
+	}
+
+	// Reductions accumulate in double precision.
+	big := F32{1 << 24, 1, 1}
+	if big.Sum() != 1<<24+2 {
+		t.Error("Bad sum:", big.Sum())
+	}
+
+	p := F32{0, float32(math.Log(3))}
+	if lse := p.Softmax(); math.Abs(lse-math.Log(4)) > 1e-6 || !p.Eq(F32{0.25, 0.75}, 1e-6) {
+		t.Error("Bad softmax:", lse, p)
+	}
+}
+
+func BenchmarkDotProductF32(b *testing.B) {
+	v1, v2 := benchmarkVectors()
+	x, y := v1.ToF32(), v2.ToF32()
+	for i := 0; i < b.N; i++ {
+		x.DotProduct(y)
+	}
+}
+
+func randomVector(r *rand.Rand, n int) F64 {
+	v := Zeroes(n)
+	for i := range v {
+		v[i] = r.NormFloat64()
+	}
+	return v
+}
+
+// All backends must give bit-identical results.
+func TestKernels(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, k := range availableKernels() {
+		for _, n := range []int{0, 1, 3, 7, 8, 9, 15, 16, 17, 31, 64, 1001} {
+			x, y := randomVector(r, n), randomVector(r, n)
+
+			naive := 0.0
+			for i := range x {
+				naive += x[i] * y[i]
+			}
+			if d := k.dot(x, y); d != dotGo(x, y) || math.Abs(d-naive) > 1e-9 {
+				t.Error(k.name, n, "bad dot product:", d, dotGo(x, y), naive)
+			}
+			if d := k.dist2(x, y); d != dist2Go(x, y) {
+				t.Error(k.name, n, "bad squared distance:", d, dist2Go(x, y))
+			}
+
+			y1 := y.Copy()
+			axpyGo(0.3, x, y1)
+			k.axpy(0.3, x, y)
+			if !y.Eq(y1, 0) {
+				t.Error(k.name, n, "bad axpy:", y, y1)
+			}
+		}
+	}
+}
+
+func BenchmarkKernels(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	for _, n := range []int{4, 16, 64, 256, 1024, 16384} {
+		x, y := randomVector(r, n), randomVector(r, n)
+		for _, k := range availableKernels() {
+			b.Run(fmt.Sprintf("dot/%d/%s", n, k.name), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					k.dot(x, y)
+				}
+			})
+			b.Run(fmt.Sprintf("dist2/%d/%s", n, k.name), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					k.dist2(x, y)
+				}
+			})
+			b.Run(fmt.Sprintf("axpy/%d/%s", n, k.name), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					k.axpy(1e-9, x, y)
+				}
+			})
+		}
+	}
+}
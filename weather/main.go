@@ -5,6 +5,7 @@ package main
 
 import (
 	"fmt"
+	"github.com/deboshire/exp/math/matrix"
 	"math"
 	"math/rand"
 	"time"
@@ -93,25 +94,31 @@ func StationaryDistr(ch MarkovChain, count int) []float64 {
 	return res
 }
 
-func Square(n int, p []float64) []float64 {
-	res := make([]float64, n*n)
-	for i := 0; i < n; i++ {
-		for j := 0; j < n; j++ {
-			ind := i*n + j
-			for k := 0; k < n; k++ {
-				res[ind] += p[i*n+k] * p[k*n+j]
-			}
-		}
-	}
-	return res
+func Square(p *matrix.Dense) *matrix.Dense {
+	return matrix.Mul(p, p)
 }
 
 func StationaryDistr2(ch MarkovChain, count int) []float64 {
-	p := ch.Trans()
+	p := matrix.NewFromData(ch.States(), ch.States(), ch.Trans())
 	for i := 0; i < count; i++ {
-		p = Square(ch.States(), p)
+		p = Square(p)
 	}
-	return p[0:ch.States()]
+	return p.Row(0)
+}
+
+// Exact solution of pi*P = pi with pi summing to 1: the transposed system
+// (P^T - I)*pi = 0 has rank n-1, so its last equation is replaced by the
+// normalization.
+func StationaryDistr3(ch MarkovChain) []float64 {
+	n := ch.States()
+	a := matrix.NewFromData(n, n, ch.Trans()).T().Copy()
+	a.Sub(matrix.Identity(n))
+	for j := 0; j < n; j++ {
+		a.Set(n-1, j, 1)
+	}
+	b := make([]float64, n)
+	b[n-1] = 1
+	return matrix.NewLU(a).SolveVec(b)
 }
 
 // Exercise 2.8.2 (b)
@@ -144,6 +151,10 @@ func PrintStationaryDistr2(ch MarkovChain, names []string, count int) {
 	PrintDistr(StationaryDistr2(ch, count), names)
 }
 
+func PrintStationaryDistr3(ch MarkovChain, names []string) {
+	PrintDistr(StationaryDistr3(ch), names)
+}
+
 func PrintEntropy(p []float64) {
 	var res float64
 	for _, v := range p {
@@ -222,6 +233,7 @@ func main() {
 	Simulate(ch, weather, 20)
 	PrintStationaryDistr(ch, weather, 100000000)
 	PrintStationaryDistr2(ch, weather, 10)
+	PrintStationaryDistr3(ch, weather)
 	PrintStationaryEntropy(ch)
 
 	SimulateMeasurements(ch, mm, weather, 30)